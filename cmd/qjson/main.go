@@ -1,20 +1,35 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"github.com/qjson/qjson-go/qjson"
 )
 
 func printHelp(w io.Writer) {
-	fmt.Fprintf(w, "Usage: qjson <qjson file> | -v | -? | --help\n")
+	fmt.Fprintf(w, "Usage: qjson [--atomic | --format | --from-json [options]] <file> | -v | -? | --help\n")
 	fmt.Fprintf(w, "Print the qjson file content converted to JSON to stdout. "+
 		"In  case of error, print an error message to stderr.\n")
 	fmt.Fprintf(w, "  -v           outputs the version.\n")
 	fmt.Fprintf(w, "  -?, --help   outputs this help message.\n")
+	fmt.Fprintf(w, "  --atomic     writes the JSON only when the whole qjson file is valid,\n")
+	fmt.Fprintf(w, "               keeping it in memory. By default, the JSON is written as\n")
+	fmt.Fprintf(w, "               it is produced and a partial JSON may be written before\n")
+	fmt.Fprintf(w, "               an error.\n")
+	fmt.Fprintf(w, "  --format     outputs the canonical formatting of the qjson file instead.\n")
+	fmt.Fprintf(w, "  --from-json  converts the JSON file content to qjson instead.\n")
+	fmt.Fprintf(w, "\nOptions of --from-json:\n")
+	fmt.Fprintf(w, "  --indent=<s>   indentation of nested values, two spaces by default,\n")
+	fmt.Fprintf(w, "                 \\t for a tab.\n")
+	fmt.Fprintf(w, "  --commas       separate members and array values with commas.\n")
+	fmt.Fprintf(w, "  --quote=<q>    quoting of names and strings: none (default), double\n")
+	fmt.Fprintf(w, "                 or single.\n")
 	fmt.Fprintf(w, "\nReturn status is 0 when the convertion was successful, 1 otherwise\n")
 }
 
@@ -27,7 +42,28 @@ func argsContain(args []string, val string) bool {
 	return false
 }
 
-func readFile(fileName string) ([]byte, error) {
+// removeArg returns args without the arguments equal to val.
+func removeArg(args []string, val string) []string {
+	var res []string
+	for _, arg := range args {
+		if arg != val {
+			res = append(res, arg)
+		}
+	}
+	return res
+}
+
+// printError prints err to stderr followed by the lines of src where the
+// error is located, when src is not nil.
+func printError(err error, src []byte) {
+	msg := err.Error()
+	if src != nil {
+		msg = qjson.FormatError(err, src)
+	}
+	fmt.Fprintf(os.Stderr, "qjson: %s\n", msg)
+}
+
+func openFile(fileName string) (*os.File, error) {
 	st, err := os.Stat(fileName)
 	if err != nil {
 		return nil, err
@@ -36,50 +72,126 @@ func readFile(fileName string) ([]byte, error) {
 	if !mode.IsRegular() {
 		return nil, fmt.Errorf("error: file '%s' is not a regular file", fileName)
 	}
-	text, err := ioutil.ReadFile(fileName)
-	if err != nil {
-		return nil, err
-	}
-	return text, nil
+	return os.Open(fileName)
 }
 
-func readStdIn() ([]byte, error) {
-	return ioutil.ReadAll(os.Stdin)
+// fromJSONArgs parses the --from-json options in args and returns the
+// remaining arguments.
+func fromJSONArgs(args []string) (*qjson.FromJSONOptions, []string, error) {
+	opts := &qjson.FromJSONOptions{}
+	var rest []string
+	for _, arg := range args {
+		switch {
+		case arg == "--from-json":
+		case arg == "--commas":
+			opts.Commas = true
+		case strings.HasPrefix(arg, "--indent="):
+			opts.Indent = strings.ReplaceAll(strings.TrimPrefix(arg, "--indent="), `\t`, "\t")
+		case strings.HasPrefix(arg, "--quote="):
+			switch q := strings.TrimPrefix(arg, "--quote="); q {
+			case "none":
+				opts.Quote = qjson.Quoteless
+			case "double":
+				opts.Quote = qjson.DoubleQuoted
+			case "single":
+				opts.Quote = qjson.SingleQuoted
+			default:
+				return nil, nil, fmt.Errorf("invalid quote '%s'", q)
+			}
+		case strings.HasPrefix(arg, "--"):
+			return nil, nil, fmt.Errorf("unknown option '%s'", arg)
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return opts, rest, nil
 }
 
 func main() {
-	var qjsonText []byte
-	var err error
+	var in io.Reader = os.Stdin
 
-	if len(os.Args) > 2 {
-		fmt.Fprintf(os.Stderr, "error: require a file name or an option as argument\n")
-		printHelp(os.Stderr)
-		os.Exit(1)
-	}
-	if argsContain(os.Args, "-?") || argsContain(os.Args, "--help") {
+	args := os.Args[1:]
+	if argsContain(args, "-?") || argsContain(args, "--help") {
 		printHelp(os.Stdout)
 		os.Exit(0)
 	}
-	if argsContain(os.Args, "-v") {
+	if argsContain(args, "-v") {
 		fmt.Println(qjson.Version())
 		os.Exit(0)
 	}
-
-	if len(os.Args) == 2 {
-		qjsonText, err = readFile(os.Args[1])
-	} else {
-		qjsonText, err = readStdIn()
+	format := argsContain(args, "--format")
+	if format {
+		args = removeArg(args, "--format")
 	}
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+	atomic := argsContain(args, "--atomic")
+	if atomic {
+		args = removeArg(args, "--atomic")
+	}
+	var opts *qjson.FromJSONOptions
+	if argsContain(args, "--from-json") {
+		var err error
+		if opts, args, err = fromJSONArgs(args); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			printHelp(os.Stderr)
+			os.Exit(1)
+		}
+	}
+	if format && opts != nil {
+		fmt.Fprintf(os.Stderr, "error: --format and --from-json are exclusive\n")
+		printHelp(os.Stderr)
 		os.Exit(1)
 	}
-
-	jsonText, err := qjson.Decode(qjsonText)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "qjson: %s\n", err)
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "error: require a file name or an option as argument\n")
+		printHelp(os.Stderr)
 		os.Exit(1)
 	}
 
-	fmt.Println(string(jsonText))
+	if len(args) == 1 {
+		f, err := openFile(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if format || opts != nil {
+		src, err := ioutil.ReadAll(in)
+		var text []byte
+		if err == nil {
+			if format {
+				text, err = qjson.Format(src)
+			} else {
+				text, err = qjson.FromJSON(src, opts)
+			}
+		}
+		if err != nil {
+			printError(err, src)
+			os.Exit(1)
+		}
+		out.Write(text)
+		out.Flush()
+		return
+	}
+	// the JSON is streamed, or written only when the decoding succeeded
+	var w io.Writer = out
+	var buf bytes.Buffer
+	if atomic {
+		w = &buf
+	}
+	if err := qjson.NewDecoder(in).WriteJSONTo(w); err != nil {
+		var src []byte
+		if len(args) == 1 {
+			// the file is read again to show the lines of the error
+			src, _ = ioutil.ReadFile(args[0])
+		}
+		printError(err, src)
+		os.Exit(1)
+	}
+	buf.WriteTo(out)
+	out.WriteByte('\n')
+	out.Flush()
 }
@@ -0,0 +1,84 @@
+package qjson
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+// A Decoder reads and decodes a QJSON text from an input stream. The input
+// is read incrementally so that the memory used is bounded by the size of
+// the longest token, and not by the size of the input or of its lines.
+type Decoder struct {
+	e    engine
+	r    io.Reader
+	opts DecodeOptions
+	used bool
+}
+
+// NewDecoder returns a new decoder that reads from r. The whole input
+// stream is one QJSON text.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetOptions sets the decoding options of the input stream. It must be
+// called before the input is decoded.
+func (d *Decoder) SetOptions(opts DecodeOptions) {
+	d.opts = opts
+}
+
+// errDecoderUsed is returned when the input stream was already decoded.
+var errDecoderUsed = errors.New("qjson: Decoder input already decoded")
+
+// start prepares the engine to decode the input stream with builder b.
+func (d *Decoder) start(b builder) error {
+	if d.used {
+		return errDecoderUsed
+	}
+	d.used = true
+	d.e.opts = d.opts
+	d.e.initReader(d.r)
+	d.e.b = b
+	return nil
+}
+
+// Decode reads the QJSON text from its input and stores the result in the
+// value pointed to by v. See Unmarshal for the conversion rules.
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	if err := d.start(&valueBuilder{root: rv}); err != nil {
+		return err
+	}
+	d.e.document()
+	if d.e.rerr != nil {
+		return d.e.rerr
+	}
+	return d.e.err()
+}
+
+// WriteJSONTo reads the QJSON text from its input and writes the
+// corresponding JSON text to w. The JSON text is written as it is
+// produced. When an error is returned, the JSON text written to w
+// is truncated.
+func (d *Decoder) WriteJSONTo(w io.Writer) error {
+	b := &jsonBuilder{w: w}
+	if err := d.start(b); err != nil {
+		return err
+	}
+	d.e.document()
+	if d.e.rerr != nil {
+		return d.e.rerr
+	}
+	if b.err != nil {
+		return b.err
+	}
+	if err := d.e.err(); err != nil {
+		return err
+	}
+	b.flush(&d.e)
+	return b.err
+}
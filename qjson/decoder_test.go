@@ -0,0 +1,147 @@
+package qjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoderWriteJSONTo(t *testing.T) {
+	tests := []string{
+		// 0
+		"",
+		"a:b",
+		"a:{b:[c,d]}\ne: 'f' // comment\n",
+		"x: (2+3) * 2\nd 1: 2m 2\n/* multi\nline */ d 2: 1h 1m 1s",
+		"t: [1970-01-02T, 1970-01-01T00:01:02, 1970-01-01T01:00:00+01:00]\n",
+		// 5
+		"a:\n  `\\n # multiline\n  the \n  brown\n  fox\n  `\nb: c\r\n",
+		"é:\u00A0ü\u00A0,\"\\u00aB\": x",
+		"a:{b:{}",
+		"a:[",
+		"a:\n`\\n\nthe `\\example`\\\n`",
+		// 10
+		"a: b\n\n\n 2020-12-23T15:40:05:test",
+		"a: 1h2m * 2",
+		"a: [b, ]",
+		"tête\f:{b:[c,d]}",
+	}
+	for i, in := range tests {
+		expOut, expErr := Decode([]byte(in))
+		for _, r := range []io.Reader{strings.NewReader(in), iotest.OneByteReader(strings.NewReader(in))} {
+			var out bytes.Buffer
+			err := NewDecoder(r).WriteJSONTo(&out)
+			if e2s(err) != e2s(expErr) {
+				t.Fatalf("%d expected err %q, got %q", i, e2s(expErr), e2s(err))
+			}
+			if err == nil && out.String() != string(expOut) {
+				t.Fatalf("%d expected out %q, got %q", i, expOut, out.String())
+			}
+		}
+	}
+}
+
+func TestDecoderLargeInput(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("values: [\n")
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&in, "  {id: %d, name: 'value %d', ratio: %d/4.}\n", i, i, i)
+	}
+	in.WriteString("]\nlast: {\n")
+	var out bytes.Buffer
+	d := NewDecoder(bytes.NewReader(in.Bytes()))
+	err := d.WriteJSONTo(&out)
+	if exp := "unclosed object at line 20003 col 7"; e2s(err) != exp {
+		t.Fatalf("expected error %q, got %q", exp, e2s(err))
+	}
+	if c := cap(d.e.in); c > 2*readSize {
+		t.Fatalf("expected input buffer capacity <= %d, got %d", 2*readSize, c)
+	}
+	in.WriteString("}")
+	expOut, err := Decode(in.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if out.Len() == 0 || !bytes.HasPrefix(expOut, out.Bytes()) {
+		t.Fatalf("expected JSON text to be written as it is produced")
+	}
+	out.Reset()
+	if err := NewDecoder(bytes.NewReader(in.Bytes())).WriteJSONTo(&out); err != nil || !bytes.Equal(out.Bytes(), expOut) {
+		t.Fatalf("unexpected output or error %v", err)
+	}
+}
+
+func TestDecoderLongLine(t *testing.T) {
+	// a single line of 260 KB, like a minified input
+	line := "a: [" + strings.Repeat("é, 1970-01-01T, 2*3, 'x', ", 10000)
+	tests := []string{
+		line + "1]",
+		line + "1}",
+		line + "1, `\\n\nx\n`]",
+		line + "1, 'x\\q']",
+		line + "\n  1}",
+	}
+	for i, in := range tests {
+		expOut, expErr := Decode([]byte(in))
+		var out bytes.Buffer
+		d := NewDecoder(strings.NewReader(in))
+		err := d.WriteJSONTo(&out)
+		if e2s(err) != e2s(expErr) {
+			t.Fatalf("%d expected err %q, got %q", i, e2s(expErr), e2s(err))
+		}
+		if err == nil && out.String() != string(expOut) {
+			t.Fatalf("%d unexpected output", i)
+		}
+		if c := cap(d.e.in); c > 2*readSize {
+			t.Fatalf("%d expected input buffer capacity <= %d, got %d", i, 2*readSize, c)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+type failingReader struct{ err error }
+
+func (r failingReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+func TestDecoderDecode(t *testing.T) {
+	in := "name: my server\nport: 0x1F90\nhosts: [a, b]\n"
+	var c testConfig
+	d := NewDecoder(iotest.HalfReader(strings.NewReader(in)))
+	if err := d.Decode(&c); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if exp := (testConfig{Name: "my server", Port: 8080, Hosts: []string{"a", "b"}}); !reflect.DeepEqual(c, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, c)
+	}
+	if err := d.Decode(&c); err != errDecoderUsed {
+		t.Fatalf("expected error %v, got %v", errDecoderUsed, err)
+	}
+	if err := NewDecoder(strings.NewReader(in)).Decode(c); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	readErr := errors.New("read error")
+	r := io.MultiReader(strings.NewReader("a: b\n"), failingReader{readErr})
+	if err := NewDecoder(r).Decode(&c); err != readErr {
+		t.Fatalf("expected error %v, got %v", readErr, err)
+	}
+	r = io.MultiReader(strings.NewReader("a: b\n"), failingReader{readErr})
+	if err := NewDecoder(r).WriteJSONTo(ioutil.Discard); err != readErr {
+		t.Fatalf("expected error %v, got %v", readErr, err)
+	}
+	if err := NewDecoder(strings.NewReader(in)).WriteJSONTo(failingWriter{}); err != io.ErrShortWrite {
+		t.Fatalf("expected error %v, got %v", io.ErrShortWrite, err)
+	}
+}
@@ -0,0 +1,434 @@
+package qjson
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Parse parses the QJSON text in input and returns its document tree.
+func Parse(input []byte, opts ...ParseOption) (*Document, error) {
+	var c parseConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	var e engine
+	e.keepComments = c.comments
+	e.allErrors = c.allErrors
+	e.opts = c.opts
+	e.init(input)
+	b := &treeBuilder{doc: &Document{}}
+	e.b = b
+	e.document()
+	if err := e.errList(); err != nil {
+		return nil, err
+	}
+	b.takeComments(&e)
+	b.doc.Trailing, b.pending = b.pending, nil
+	b.doc.src = input
+	b.doc.opts = opts
+	return b.doc, nil
+}
+
+// A ParseOption is an option of Parse.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	comments  bool
+	allErrors bool
+	opts      DecodeOptions
+}
+
+// WithComments makes Parse keep the comments and attach them to the
+// nodes of the document tree. A comment following a member or an array
+// value on its last line is a trailing comment of the member or value.
+// The other comments are leading comments of the following node, or
+// inner comments of the object or array when no node follows them, or
+// trailing comments of the document when they follow the root value.
+func WithComments() ParseOption {
+	return func(c *parseConfig) { c.comments = true }
+}
+
+// WithDecodeOptions makes Parse decode the QJSON text with the decoding
+// options opts.
+func WithDecodeOptions(opts DecodeOptions) ParseOption {
+	return func(c *parseConfig) { c.opts = opts }
+}
+
+// WithAllErrors makes Parse report all the syntax errors of the QJSON
+// text in an ErrorList, as DecodeAll does.
+func WithAllErrors() ParseOption {
+	return func(c *parseConfig) { c.allErrors = true }
+}
+
+// A Document is the tree of a QJSON text.
+type Document struct {
+	Root     Node       // the top level object, or the root value
+	Trailing []*Comment // comments following the root value and its line
+
+	src  []byte        // the QJSON text
+	opts []ParseOption // options of Parse
+}
+
+// A Node is an element of the document tree. It is one of *Object,
+// *Member, *Array, *String, *Number, *Literal or *Multiline.
+type Node interface {
+	// Position returns the position of the first byte of the node.
+	Position() Pos
+	// EndPosition returns the position following the last byte of the node.
+	EndPosition() Pos
+}
+
+// A Comment is a #..., //... or /*...*/ comment.
+type Comment struct {
+	Pos  Pos
+	Text string // the comment as written, without the ending newline
+}
+
+// Comments are the comments attached to a node.
+type Comments struct {
+	Leading  []*Comment // comments preceding the node
+	Trailing []*Comment // comments following the node on its last line
+}
+
+func (c *Comments) comments() *Comments { return c }
+
+// commented is a node with attached comments.
+type commented interface {
+	comments() *Comments
+}
+
+// An Object is a sequence of members enclosed in braces. The top level
+// object has no braces.
+type Object struct {
+	Comments
+	Pos     Pos
+	End     Pos
+	Members []*Member
+	Inner   []*Comment // comments following the last member
+}
+
+// A Member is an identifier and value pair of an object.
+type Member struct {
+	Comments
+	Pos   Pos
+	End   Pos
+	Name  *String
+	Value Node
+}
+
+// An Array is a sequence of values enclosed in square brackets.
+type Array struct {
+	Comments
+	Pos    Pos
+	End    Pos
+	Values []Node
+	Inner  []*Comment // comments following the last value
+}
+
+// QuoteKind is the kind of quotes of a string.
+type QuoteKind byte
+
+// The kinds of quotes of a string.
+const (
+	Quoteless QuoteKind = iota
+	DoubleQuoted
+	SingleQuoted
+)
+
+var quoteKindStr = [...]string{"Quoteless", "DoubleQuoted", "SingleQuoted"}
+
+func (q QuoteKind) String() string {
+	if int(q) < len(quoteKindStr) {
+		return quoteKindStr[q]
+	}
+	return "QuoteKind(" + strconv.Itoa(int(q)) + ")"
+}
+
+// A String is a quoteless, double quoted or single quoted string.
+type String struct {
+	Comments
+	Pos   Pos
+	End   Pos
+	Quote QuoteKind
+	Raw   string // the string as written, including quotes
+	Value string // the string value
+}
+
+// A Number is a number or a number expression. Its value is the JSON
+// number resulting of the evaluation of the expression.
+type Number struct {
+	Comments
+	Pos   Pos
+	End   Pos
+	Raw   string // the number expression as written
+	Value string // the JSON number text
+}
+
+// Float64 returns the value of the number as a float64.
+func (n *Number) Float64() (float64, error) {
+	return strconv.ParseFloat(n.Value, 64)
+}
+
+// Int64 returns the value of the number as an int64.
+func (n *Number) Int64() (int64, error) {
+	return strconv.ParseInt(n.Value, 10, 64)
+}
+
+// A Literal is one of the quoteless literal values true, false or null,
+// or their alternate forms like yes, off or NULL.
+type Literal struct {
+	Comments
+	Pos   Pos
+	End   Pos
+	Raw   string // the literal as written
+	Value string // "true", "false" or "null"
+}
+
+// A Multiline is a multiline string.
+type Multiline struct {
+	Comments
+	Pos     Pos
+	End     Pos
+	Margin  string // the whitespaces in front of the opening `
+	Raw     string // the multiline string as written, from ` to `
+	Newline string // the newline of the value, "\n" or "\r\n"
+	Value   string // the string value
+}
+
+// Position returns the position of the opening brace of the object.
+func (n *Object) Position() Pos { return n.Pos }
+
+// Position returns the position of the member identifier.
+func (n *Member) Position() Pos { return n.Pos }
+
+// Position returns the position of the opening square bracket of the array.
+func (n *Array) Position() Pos { return n.Pos }
+
+// Position returns the position of the first byte of the string.
+func (n *String) Position() Pos { return n.Pos }
+
+// Position returns the position of the first byte of the number expression.
+func (n *Number) Position() Pos { return n.Pos }
+
+// Position returns the position of the first byte of the literal.
+func (n *Literal) Position() Pos { return n.Pos }
+
+// Position returns the position of the opening ` of the multiline string.
+func (n *Multiline) Position() Pos { return n.Pos }
+
+// EndPosition returns the position following the closing brace of the object.
+func (n *Object) EndPosition() Pos { return n.End }
+
+// EndPosition returns the position following the member value.
+func (n *Member) EndPosition() Pos { return n.End }
+
+// EndPosition returns the position following the closing square bracket of the array.
+func (n *Array) EndPosition() Pos { return n.End }
+
+// EndPosition returns the position following the last byte of the string.
+func (n *String) EndPosition() Pos { return n.End }
+
+// EndPosition returns the position following the number expression.
+func (n *Number) EndPosition() Pos { return n.End }
+
+// EndPosition returns the position following the literal.
+func (n *Literal) EndPosition() Pos { return n.End }
+
+// EndPosition returns the position following the closing ` of the multiline string.
+func (n *Multiline) EndPosition() Pos { return n.End }
+
+// treeBuilder builds the document tree.
+type treeBuilder struct {
+	doc      *Document
+	stack    []treeFrame
+	member   *Member    // member expecting its value
+	pending  []*Comment // comments preceding the next node
+	last     commented  // last completed member, array value or root, or nil
+	lastLine int        // index of the last line of last
+}
+
+// treeFrame is an open object or array.
+type treeFrame struct {
+	n    Node
+	item commented // member or array value of n, or n for the root
+}
+
+// takeComments attaches the comments met by the tokenizer as trailing
+// comments of the last completed member or value, or keeps them as
+// pending comments of the next node.
+func (b *treeBuilder) takeComments(e *engine) {
+	for _, s := range e.comments {
+		c := &Comment{Pos: e.position(s.pos), Text: string(e.text(s.b, s.e))}
+		if b.last != nil && s.l == b.lastLine {
+			t := b.last.comments()
+			t.Trailing = append(t.Trailing, c)
+		} else {
+			b.pending = append(b.pending, c)
+		}
+	}
+	e.comments = e.comments[:0]
+}
+
+// add adds n to the open object or array and returns its member or array
+// value. It returns n when n is the root.
+func (b *treeBuilder) add(n Node) commented {
+	b.takePending(n.(commented))
+	if len(b.stack) == 0 {
+		b.doc.Root = n
+		return n.(commented)
+	}
+	switch x := b.stack[len(b.stack)-1].n.(type) {
+	case *Object:
+		m := b.member
+		m.Value = n
+		b.member = nil
+		return m
+	case *Array:
+		x.Values = append(x.Values, n)
+	}
+	return n.(commented)
+}
+
+// takePending sets the pending comments as leading comments of n.
+func (b *treeBuilder) takePending(n commented) {
+	n.comments().Leading = b.pending
+	b.pending = nil
+	b.last = nil
+}
+
+// addValue adds the scalar value n.
+func (b *treeBuilder) addValue(e *engine, n Node) {
+	b.takeComments(e)
+	b.done(b.add(n), n.EndPosition())
+}
+
+// done records that the member or array value item ends at end.
+func (b *treeBuilder) done(item commented, end Pos) {
+	if m, ok := item.(*Member); ok {
+		m.End = end
+	}
+	b.last = item
+	b.lastLine = end.Line - 1
+}
+
+// tokenEnd returns the position following the current token.
+func tokenEnd(e *engine) Pos {
+	switch e.tk.tag {
+	case tagQuotelessString:
+		p := e.tk.pos
+		p.b += len(e.tk.val.([]byte))
+		return e.position(p)
+	case tagError:
+		return e.position(e.tk.pos) // end of input
+	}
+	return e.position(e.tokenizer.pos)
+}
+
+func (b *treeBuilder) open(n Node) {
+	b.stack = append(b.stack, treeFrame{n: n, item: b.add(n)})
+}
+
+// close closes the open object or array and returns its inner comments
+// and end position.
+func (b *treeBuilder) close(e *engine) ([]*Comment, Pos) {
+	b.takeComments(e)
+	f := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
+	inner := b.pending
+	b.pending = nil
+	end := tokenEnd(e)
+	b.done(f.item, end)
+	return inner, end
+}
+
+func (b *treeBuilder) openObject(e *engine) {
+	n := &Object{Pos: Pos{Line: 1, Column: 1}}
+	// the comments in front of the first member of the implicit top level
+	// object are left to it
+	if e.tk.tag == tagOpenBrace {
+		b.takeComments(e)
+		n.Pos = e.position(e.tk.pos)
+	}
+	b.open(n)
+}
+
+func (b *treeBuilder) closeObject(e *engine) {
+	n := b.stack[len(b.stack)-1].n.(*Object)
+	n.Inner, n.End = b.close(e)
+}
+
+func (b *treeBuilder) openArray(e *engine) {
+	b.takeComments(e)
+	b.open(&Array{Pos: e.position(e.tk.pos)})
+}
+
+func (b *treeBuilder) closeArray(e *engine) {
+	n := b.stack[len(b.stack)-1].n.(*Array)
+	n.Inner, n.End = b.close(e)
+}
+
+func (b *treeBuilder) memberName(e *engine) {
+	b.takeComments(e)
+	s := b.stringNode(e)
+	if s == nil {
+		return
+	}
+	o := b.stack[len(b.stack)-1].n.(*Object)
+	b.member = &Member{Pos: s.Pos, Name: s}
+	b.takePending(b.member)
+	o.Members = append(o.Members, b.member)
+}
+
+// stringNode returns the string node of the current token, or nil if the
+// string is invalid.
+func (b *treeBuilder) stringNode(e *engine) *String {
+	s := &String{Pos: e.position(e.tk.pos), End: tokenEnd(e), Raw: string(e.tk.val.([]byte))}
+	switch e.tk.tag {
+	case tagDoubleQuotedString:
+		s.Quote = DoubleQuoted
+	case tagSingleQuotedString:
+		s.Quote = SingleQuoted
+	}
+	var ok bool
+	if s.Value, ok = e.stringValue(); !ok {
+		return nil
+	}
+	return s
+}
+
+func (b *treeBuilder) stringValue(e *engine) {
+	if e.tk.tag != tagMultilineString {
+		if s := b.stringNode(e); s != nil {
+			b.addValue(e, s)
+		}
+		return
+	}
+	val := e.tk.val.([]byte)
+	margin := val[:bytes.IndexByte(val, '`')]
+	n := &Multiline{
+		Pos:     e.position(e.tk.pos),
+		End:     tokenEnd(e),
+		Margin:  string(margin),
+		Raw:     string(val[len(margin):]),
+		Newline: "\n",
+	}
+	p := val[len(margin)+1:]
+	for w := whitespace(p); w != 0; w = whitespace(p) {
+		p = p[w:]
+	}
+	if newlineSpecifier(p) == 4 {
+		n.Newline = "\r\n"
+	}
+	var ok bool
+	if n.Value, ok = e.stringValue(); ok {
+		b.addValue(e, n)
+	}
+}
+
+func (b *treeBuilder) literalValue(e *engine, lit string) {
+	b.addValue(e, &Literal{Pos: e.position(e.tk.pos), End: tokenEnd(e), Raw: string(e.tk.val.([]byte)), Value: lit})
+}
+
+func (b *treeBuilder) numberValue(e *engine, num string) {
+	b.addValue(e, &Number{Pos: e.position(e.tk.pos), End: tokenEnd(e), Raw: string(e.tk.val.([]byte)), Value: num})
+}
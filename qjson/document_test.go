@@ -0,0 +1,215 @@
+package qjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	in := "name: 'my server'\nport: 0x1F90 # comment\nhosts: [a, \"b\", yes]\nsub: {x: 2 * 3}\ntext:\n  `\\r\\n\n  line\n  `"
+	doc, err := Parse([]byte(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := &Object{
+		Pos: Pos{Offset: 0, Line: 1, Column: 1},
+		End: Pos{Offset: 102, Line: 8, Column: 4},
+		Members: []*Member{
+			{
+				Pos:   Pos{Offset: 0, Line: 1, Column: 1},
+				End:   Pos{Offset: 17, Line: 1, Column: 18},
+				Name:  &String{Pos: Pos{Offset: 0, Line: 1, Column: 1}, End: Pos{Offset: 4, Line: 1, Column: 5}, Raw: "name", Value: "name"},
+				Value: &String{Pos: Pos{Offset: 6, Line: 1, Column: 7}, End: Pos{Offset: 17, Line: 1, Column: 18}, Quote: SingleQuoted, Raw: "'my server'", Value: "my server"},
+			},
+			{
+				Pos:   Pos{Offset: 18, Line: 2, Column: 1},
+				End:   Pos{Offset: 30, Line: 2, Column: 13},
+				Name:  &String{Pos: Pos{Offset: 18, Line: 2, Column: 1}, End: Pos{Offset: 22, Line: 2, Column: 5}, Raw: "port", Value: "port"},
+				Value: &Number{Pos: Pos{Offset: 24, Line: 2, Column: 7}, End: Pos{Offset: 30, Line: 2, Column: 13}, Raw: "0x1F90", Value: "8080"},
+			},
+			{
+				Pos:  Pos{Offset: 41, Line: 3, Column: 1},
+				End:  Pos{Offset: 61, Line: 3, Column: 21},
+				Name: &String{Pos: Pos{Offset: 41, Line: 3, Column: 1}, End: Pos{Offset: 46, Line: 3, Column: 6}, Raw: "hosts", Value: "hosts"},
+				Value: &Array{
+					Pos: Pos{Offset: 48, Line: 3, Column: 8},
+					End: Pos{Offset: 61, Line: 3, Column: 21},
+					Values: []Node{
+						&String{Pos: Pos{Offset: 49, Line: 3, Column: 9}, End: Pos{Offset: 50, Line: 3, Column: 10}, Raw: "a", Value: "a"},
+						&String{Pos: Pos{Offset: 52, Line: 3, Column: 12}, End: Pos{Offset: 55, Line: 3, Column: 15}, Quote: DoubleQuoted, Raw: `"b"`, Value: "b"},
+						&Literal{Pos: Pos{Offset: 57, Line: 3, Column: 17}, End: Pos{Offset: 60, Line: 3, Column: 20}, Raw: "yes", Value: "true"},
+					},
+				},
+			},
+			{
+				Pos:  Pos{Offset: 62, Line: 4, Column: 1},
+				End:  Pos{Offset: 77, Line: 4, Column: 16},
+				Name: &String{Pos: Pos{Offset: 62, Line: 4, Column: 1}, End: Pos{Offset: 65, Line: 4, Column: 4}, Raw: "sub", Value: "sub"},
+				Value: &Object{
+					Pos: Pos{Offset: 67, Line: 4, Column: 6},
+					End: Pos{Offset: 77, Line: 4, Column: 16},
+					Members: []*Member{
+						{
+							Pos:   Pos{Offset: 68, Line: 4, Column: 7},
+							End:   Pos{Offset: 76, Line: 4, Column: 15},
+							Name:  &String{Pos: Pos{Offset: 68, Line: 4, Column: 7}, End: Pos{Offset: 69, Line: 4, Column: 8}, Raw: "x", Value: "x"},
+							Value: &Number{Pos: Pos{Offset: 71, Line: 4, Column: 10}, End: Pos{Offset: 76, Line: 4, Column: 15}, Raw: "2 * 3", Value: "6"},
+						},
+					},
+				},
+			},
+			{
+				Pos:  Pos{Offset: 78, Line: 5, Column: 1},
+				End:  Pos{Offset: 102, Line: 8, Column: 4},
+				Name: &String{Pos: Pos{Offset: 78, Line: 5, Column: 1}, End: Pos{Offset: 82, Line: 5, Column: 5}, Raw: "text", Value: "text"},
+				Value: &Multiline{
+					Pos:     Pos{Offset: 86, Line: 6, Column: 3},
+					End:     Pos{Offset: 102, Line: 8, Column: 4},
+					Margin:  "  ",
+					Raw:     "`\\r\\n\n  line\n  `",
+					Newline: "\r\n",
+					Value:   "line\r\n",
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc.Root, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, doc.Root)
+	}
+	n := exp.Members[1].Value.(*Number)
+	if v, err := n.Int64(); err != nil || v != 8080 {
+		t.Fatalf("expected 8080, got %d, %v", v, err)
+	}
+	if v, err := n.Float64(); err != nil || v != 8080 {
+		t.Fatalf("expected 8080, got %g, %v", v, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		// 0
+		{in: "a: {b: c", err: "unclosed object at line 1 col 4"},
+		{in: "a: [b", err: "unclosed array at line 1 col 5"},
+		{in: "a: \"\\x\"", err: "invalid escape squence at line 1 col 5"},
+		{in: "a: 1/0", err: "division by zero at line 1 col 5"},
+	}
+	for i, test := range tests {
+		doc, err := Parse([]byte(test.in))
+		if out := e2s(err); out != test.err {
+			t.Fatalf("%d expected err %q, got %q", i, test.err, out)
+		}
+		if doc != nil {
+			t.Fatalf("%d expected nil document", i)
+		}
+	}
+	doc, err := Parse(nil)
+	if err != nil || !reflect.DeepEqual(doc.Root, &Object{Pos: Pos{Line: 1, Column: 1}, End: Pos{Line: 1, Column: 1}}) {
+		t.Fatalf("expected empty object, got %+v, %v", doc, err)
+	}
+	doc, err = Parse([]byte("a: {b: c\nd: [1, }]\ne: 1/0\n"), WithAllErrors(), WithComments())
+	exp := ErrorList{
+		{Code: ErrUnclosedObject, Pos: Pos{Offset: 3, Line: 1, Column: 4}},
+		{Code: ErrExpectValueAfterComma, Pos: Pos{Offset: 16, Line: 2, Column: 8}},
+		{Code: ErrDivisionByZero, Pos: Pos{Offset: 23, Line: 3, Column: 5}},
+	}
+	l, ok := err.(ErrorList)
+	if doc != nil || !ok || len(l) != len(exp) {
+		t.Fatalf("expected errors %v, got %v", exp, err)
+	}
+	for i := range exp {
+		if *l[i] != *exp[i] {
+			t.Fatalf("%d expected error %+v, got %+v", i, *exp[i], *l[i])
+		}
+	}
+}
+
+func TestParseWithComments(t *testing.T) {
+	in := `# header
+
+a: 1 # one
+// before b
+b: { # open
+  c: [x, # x
+    y
+    /* end of c */
+  ]
+  # end of b
+} // after b
+d: # before value
+  e
+/* last */`
+	doc, err := Parse([]byte(in), WithComments())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	texts := func(cs []*Comment) []string {
+		var s []string
+		for _, c := range cs {
+			s = append(s, c.Text)
+		}
+		return s
+	}
+	root := doc.Root.(*Object)
+	b := root.Members[1].Value.(*Object)
+	c := b.Members[0].Value.(*Array)
+	tests := []struct {
+		cs  []*Comment
+		exp []string
+	}{
+		// 0
+		{cs: root.Leading},
+		{cs: root.Members[0].Leading, exp: []string{"# header"}},
+		{cs: root.Members[0].Trailing, exp: []string{"# one"}},
+		{cs: root.Members[1].Leading, exp: []string{"// before b"}},
+		{cs: root.Members[1].Trailing, exp: []string{"// after b"}},
+		// 5
+		{cs: b.Members[0].Leading, exp: []string{"# open"}},
+		{cs: c.Values[0].(*String).Trailing, exp: []string{"# x"}},
+		{cs: c.Values[1].(*String).Leading},
+		{cs: c.Inner, exp: []string{"/* end of c */"}},
+		{cs: b.Inner, exp: []string{"# end of b"}},
+		// 10
+		{cs: root.Members[2].Value.(*String).Leading, exp: []string{"# before value"}},
+		{cs: root.Inner, exp: []string{"/* last */"}},
+	}
+	for i, test := range tests {
+		if res := texts(test.cs); !reflect.DeepEqual(res, test.exp) {
+			t.Fatalf("%d expected %q, got %q", i, test.exp, res)
+		}
+	}
+	if p := root.Members[1].Trailing[0].Pos; p != (Pos{Offset: 102, Line: 11, Column: 3}) {
+		t.Fatalf("unexpected comment position %v", p)
+	}
+
+	doc, err = Parse([]byte(in))
+	if err != nil || doc.Root.(*Object).Inner != nil || doc.Root.(*Object).Members[0].Trailing != nil {
+		t.Fatalf("expected no comments, got %v", err)
+	}
+
+	// comments following a root array or a root value
+	roots := []struct {
+		in       string
+		trailing []string
+		end      []string
+	}{
+		{in: "[1, 2] # c", trailing: []string{"# c"}},
+		{in: "# head\n[\n  1 # one\n]\n# tail", end: []string{"# tail"}},
+		{in: "[] /* a */ # b\n\n// c\n# d", trailing: []string{"/* a */", "# b"}, end: []string{"// c", "# d"}},
+		{in: "1 # one\n# tail", trailing: []string{"# one"}, end: []string{"# tail"}},
+	}
+	for i, test := range roots {
+		doc, err := Parse([]byte(test.in), WithComments(), WithDecodeOptions(DecodeOptions{Root: RootValue}))
+		if err != nil {
+			t.Fatalf("root %d: unexpected error: %v", i, err)
+		}
+		if res := texts(doc.Root.(commented).comments().Trailing); !reflect.DeepEqual(res, test.trailing) {
+			t.Fatalf("root %d: expected trailing %q, got %q", i, test.trailing, res)
+		}
+		if res := texts(doc.Trailing); !reflect.DeepEqual(res, test.end) {
+			t.Fatalf("root %d: expected document comments %q, got %q", i, test.end, res)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package qjson
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// A rawValue is a value of a compact JSON text produced by the engine.
+type rawValue struct {
+	kind    byte        // '{' for an object, '[' for an array, 0 otherwise
+	text    []byte      // text of a string, number or literal
+	members []rawMember // members of an object
+	values  []*rawValue // values of an array
+}
+
+// A rawMember is a member of a rawValue object.
+type rawMember struct {
+	name  string // the unquoted name
+	text  []byte // the quoted name
+	value *rawValue
+}
+
+// removes returns true if the members with the same name are reduced to
+// one member.
+func (p DuplicatePolicy) removes() bool {
+	return p == DuplicateFirstWins || p == DuplicateLastWins || p == DuplicateMerge
+}
+
+// removeDuplicates returns the compact JSON text p where the members with
+// the same name are reduced to one member with the policy.
+func removeDuplicates(p []byte, policy DuplicatePolicy) []byte {
+	v, _ := readRawValue(p)
+	v.removeDuplicates(policy)
+	var b bytes.Buffer
+	b.Grow(len(p))
+	v.writeTo(&b)
+	return b.Bytes()
+}
+
+// readRawValue reads the value at the start of p and returns it with the
+// rest of p.
+func readRawValue(p []byte) (*rawValue, []byte) {
+	v := &rawValue{kind: p[0]}
+	switch p[0] {
+	case '{':
+		p = p[1:]
+		for p[0] != '}' {
+			if p[0] == ',' {
+				p = p[1:]
+			}
+			n := rawStringLen(p)
+			m := rawMember{text: p[:n]}
+			json.Unmarshal(m.text, &m.name)
+			m.value, p = readRawValue(p[n+1:])
+			v.members = append(v.members, m)
+		}
+		return v, p[1:]
+	case '[':
+		p = p[1:]
+		for p[0] != ']' {
+			if p[0] == ',' {
+				p = p[1:]
+			}
+			var x *rawValue
+			x, p = readRawValue(p)
+			v.values = append(v.values, x)
+		}
+		return v, p[1:]
+	case '"':
+		n := rawStringLen(p)
+		v.kind, v.text = 0, p[:n]
+		return v, p[n:]
+	}
+	n := bytes.IndexAny(p, ",]}")
+	if n < 0 {
+		n = len(p)
+	}
+	v.kind, v.text = 0, p[:n]
+	return v, p[n:]
+}
+
+// rawStringLen returns the length of the JSON string at the start of p.
+func rawStringLen(p []byte) int {
+	for i := 1; i < len(p); i++ {
+		switch p[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(p)
+}
+
+// removeDuplicates reduces the members of v and of its values with the
+// same name to one member with the policy.
+func (v *rawValue) removeDuplicates(policy DuplicatePolicy) {
+	if v.kind == '{' {
+		index := make(map[string]int, len(v.members))
+		members := v.members[:0:0]
+		for _, m := range v.members {
+			i, ok := index[m.name]
+			if !ok {
+				index[m.name] = len(members)
+				members = append(members, m)
+				continue
+			}
+			switch {
+			case policy == DuplicateLastWins:
+				members[i].value = m.value
+			case policy == DuplicateMerge:
+				if prev := members[i].value; prev.kind == '{' && m.value.kind == '{' {
+					merged := append(prev.members[:len(prev.members):len(prev.members)], m.value.members...)
+					members[i].value = &rawValue{kind: '{', members: merged}
+				} else {
+					members[i].value = m.value
+				}
+			}
+		}
+		v.members = members
+	}
+	for _, m := range v.members {
+		m.value.removeDuplicates(policy)
+	}
+	for _, x := range v.values {
+		x.removeDuplicates(policy)
+	}
+}
+
+// writeTo writes the compact JSON text of v to b.
+func (v *rawValue) writeTo(b *bytes.Buffer) {
+	switch v.kind {
+	case '{':
+		b.WriteByte('{')
+		for i, m := range v.members {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(m.text)
+			b.WriteByte(':')
+			m.value.writeTo(b)
+		}
+		b.WriteByte('}')
+	case '[':
+		b.WriteByte('[')
+		for i, x := range v.values {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			x.writeTo(b)
+		}
+		b.WriteByte(']')
+	default:
+		b.Write(v.text)
+	}
+}
@@ -0,0 +1,88 @@
+package qjson
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDuplicates(t *testing.T) {
+	tests := []struct {
+		in     string
+		policy DuplicatePolicy
+		out    string
+		err    string
+	}{
+		// 0
+		{in: "a: 1, a: 2", policy: DuplicateKeep, out: `{"a":1,"a":2}`},
+		{in: "a: 1, \"a\": 2", policy: DuplicateError, err: `duplicate key "a" at line 1 col 7, first at line 1 col 1`},
+		{in: "a: {b: 1}\nc: 2\n'a': 3", policy: DuplicateError, err: `duplicate key "a" at line 3 col 1, first at line 1 col 1`},
+		{in: "a: {b: 1}, c: {b: 2}, d: [{b: 3}, {b: 4}]", policy: DuplicateError, out: `{"a":{"b":1},"c":{"b":2},"d":[{"b":3},{"b":4}]}`},
+		{in: `a: {"b": 1, b: 2}`, policy: DuplicateError, err: `duplicate key "b" at line 1 col 13, first at line 1 col 5`},
+		// 5
+		{in: "a: 1, b: 2, a: 3", policy: DuplicateFirstWins, out: `{"a":1,"b":2}`},
+		{in: "a: 1, b: 2, a: 3", policy: DuplicateLastWins, out: `{"a":3,"b":2}`},
+		{in: "a: [{b: 1, b: 2}], c: x", policy: DuplicateLastWins, out: `{"a":[{"b":2}],"c":"x"}`},
+		{in: "a: {b: 1, c: {d: 2}}, a: {c: {e: 3}, b: 4}", policy: DuplicateMerge, out: `{"a":{"b":4,"c":{"d":2,"e":3}}}`},
+		{in: "a: {b: 1}, a: [2], a: {c: 3}", policy: DuplicateMerge, out: `{"a":{"c":3}}`},
+		// 10
+		{in: "a: {b: 1}, a: {b: {c: 2}}, a: {b: {d: 3}}", policy: DuplicateMerge, out: `{"a":{"b":{"c":2,"d":3}}}`},
+		{in: `a: "x,}\"", a: 'y', b: "<\/"`, policy: DuplicateLastWins, out: `{"a":"y","b":"<\/"}`},
+	}
+	for i, test := range tests {
+		out, err := DecodeWithOptions([]byte(test.in), DecodeOptions{Duplicates: test.policy})
+		if e2s(err) != test.err {
+			t.Errorf("%d expected error %q, got %q", i, test.err, e2s(err))
+		} else if err == nil && string(out) != test.out {
+			t.Errorf("%d expected output %q, got %q", i, test.out, out)
+		}
+		if err == nil {
+			var buf bytes.Buffer
+			d := NewDecoder(strings.NewReader(test.in))
+			d.SetOptions(DecodeOptions{Duplicates: test.policy})
+			if err := d.WriteJSONTo(&buf); err != nil || buf.String() != test.out {
+				t.Errorf("%d expected written output %q, got %q, %v", i, test.out, buf.String(), err)
+			}
+		}
+	}
+	_, err := DecodeWithOptions([]byte("a: 1, a: 2"), DecodeOptions{Duplicates: DuplicateError})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+}
+
+func TestUnmarshalDuplicates(t *testing.T) {
+	type inner struct {
+		B int
+		C int
+	}
+	type outer struct {
+		A inner
+		D map[string]interface{}
+	}
+	in := "a: {b: 1}, a: {c: 2}, d: {x: 1, y: {z: 2}}, d: {y: {w: 3}}"
+	tests := []struct {
+		policy DuplicatePolicy
+		out    outer
+	}{
+		{policy: DuplicateFirstWins, out: outer{A: inner{B: 1}, D: map[string]interface{}{"x": 1.0, "y": map[string]interface{}{"z": 2.0}}}},
+		{policy: DuplicateLastWins, out: outer{A: inner{C: 2}, D: map[string]interface{}{"y": map[string]interface{}{"w": 3.0}}}},
+		{policy: DuplicateMerge, out: outer{A: inner{B: 1, C: 2}, D: map[string]interface{}{"x": 1.0, "y": map[string]interface{}{"z": 2.0, "w": 3.0}}}},
+	}
+	for _, test := range tests {
+		var v outer
+		if err := UnmarshalWithOptions([]byte(in), &v, DecodeOptions{Duplicates: test.policy}); err != nil {
+			t.Fatalf("policy %d: unexpected error %v", test.policy, err)
+		}
+		if !reflect.DeepEqual(v, test.out) {
+			t.Errorf("policy %d: expected %+v, got %+v", test.policy, test.out, v)
+		}
+	}
+	var m map[string]interface{}
+	err := UnmarshalWithOptions([]byte("a: 1\na: 2"), &m, DecodeOptions{Duplicates: DuplicateError})
+	if e2s(err) != `duplicate key "a" at line 2 col 1, first at line 1 col 1` {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
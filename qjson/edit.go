@@ -0,0 +1,274 @@
+package qjson
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Print returns the QJSON text of the document. It is the text given to
+// Parse, byte for byte, modified by the edits of the document.
+func (d *Document) Print() []byte {
+	return append([]byte(nil), d.src...)
+}
+
+// SetValue replaces the value at path with value, a QJSON value text. The
+// path is a sequence of member names and array indexes. When an object has
+// multiple members with the same name, the last one is used. The rest of
+// the text is unchanged.
+func (d *Document) SetValue(path []string, value string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("%w: empty path", ErrPathNotFound)
+	}
+	if err := checkValue(value); err != nil {
+		return err
+	}
+	_, n, err := d.lookup(path)
+	if err != nil {
+		return err
+	}
+	b, e := n.Position().Offset, n.EndPosition().Offset
+	indent := d.indent(b)
+	if value[0] == '`' && d.lineStart(b)+len(indent) != b {
+		// a multiline string must start its own line
+		for b > 0 && (d.src[b-1] == ' ' || d.src[b-1] == '\t') {
+			b--
+		}
+		value = "\n  " + reindent(value, "  ")
+	}
+	return d.edit(replacement{b: b, e: e, s: reindent(value, indent)})
+}
+
+// DeleteMember removes the member or array value at path. When it is the
+// only member or value of its lines, the lines are removed with its
+// trailing comments. Its leading comments are kept.
+func (d *Document) DeleteMember(path []string) error {
+	if len(path) == 0 {
+		return fmt.Errorf("%w: empty path", ErrPathNotFound)
+	}
+	_, parent, err := d.lookup(path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	item, _, err := d.child(parent, path)
+	if err != nil {
+		return err
+	}
+	var items []Node
+	switch x := parent.(type) {
+	case *Object:
+		for _, m := range x.Members {
+			items = append(items, m)
+		}
+	case *Array:
+		items = x.Values
+	}
+	var i int
+	for items[i] != item {
+		i++
+	}
+	b, e := item.Position().Offset, item.EndPosition().Offset
+	prevComma, nextComma := -1, -1
+	if i > 0 {
+		prevComma = d.comma(items[i-1].EndPosition().Offset, b)
+	}
+	if i < len(items)-1 {
+		nextComma = d.comma(e, items[i+1].Position().Offset)
+	}
+	if ls := d.lineStart(b); ls+len(d.indent(b)) == b {
+		if le := d.restOfLine(e, nextComma); le >= 0 {
+			if prevComma >= 0 && (nextComma < 0 || nextComma >= le) {
+				// the comma separating the previous value is not needed
+				return d.edit(replacement{b: prevComma, e: prevComma + 1}, replacement{b: ls, e: le})
+			}
+			return d.edit(replacement{b: ls, e: le})
+		}
+	}
+	switch {
+	case nextComma >= 0:
+		e = nextComma + 1
+		for w := whitespace(d.src[e:]); w != 0; w = whitespace(d.src[e:]) {
+			e += w
+		}
+	case prevComma >= 0:
+		b = prevComma
+	}
+	return d.edit(replacement{b: b, e: e})
+}
+
+// InsertMember appends the member key with value, a QJSON value text, to
+// the object at path. The member is inserted on its own line when the
+// last member of the object is on its own line.
+func (d *Document) InsertMember(path []string, key, value string) error {
+	if err := checkValue(value); err != nil {
+		return err
+	}
+	_, n, err := d.lookup(path)
+	if err != nil {
+		return err
+	}
+	o, ok := n.(*Object)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotAnObject, strings.Join(path, "."))
+	}
+	text := quoteName(key) + ": " + value
+	if value[0] == '`' {
+		text = quoteName(key) + ":\n  " + reindent(value, "  ")
+	}
+	if len(o.Members) == 0 {
+		if len(path) == 0 {
+			b := len(d.src)
+			if b > 0 && d.src[b-1] != '\n' {
+				text = "\n" + text
+			}
+			return d.edit(replacement{b: b, e: b, s: text + "\n"})
+		}
+		b := o.Pos.Offset + 1
+		if o.End.Line != o.Pos.Line {
+			indent := d.indent(o.Pos.Offset) + "  "
+			text = "\n" + indent + reindent(text, indent)
+		}
+		return d.edit(replacement{b: b, e: b, s: text})
+	}
+	last := o.Members[len(o.Members)-1]
+	b, indent := last.Pos.Offset, d.indent(last.Pos.Offset)
+	if d.lineStart(b)+len(indent) == b {
+		if le := d.restOfLine(last.End.Offset, -1); le >= 0 {
+			switch {
+			case bytes.HasSuffix(d.src[:le], []byte("\r\n")):
+				text = indent + reindent(text, indent) + "\r\n"
+			case bytes.HasSuffix(d.src[:le], []byte("\n")):
+				text = indent + reindent(text, indent) + "\n"
+			default:
+				text = "\n" + indent + reindent(text, indent)
+			}
+			return d.edit(replacement{b: le, e: le, s: text})
+		}
+	}
+	return d.edit(replacement{b: last.End.Offset, e: last.End.Offset, s: ", " + reindent(text, indent)})
+}
+
+// A replacement replaces the text from b to e with s.
+type replacement struct {
+	b, e int
+	s    string
+}
+
+// edit applies the replacements, sorted by increasing offsets, and parses
+// the resulting text. The document is unchanged if the text is invalid.
+func (d *Document) edit(repls ...replacement) error {
+	var buf bytes.Buffer
+	var b int
+	for _, r := range repls {
+		buf.Write(d.src[b:r.b])
+		buf.WriteString(r.s)
+		b = r.e
+	}
+	buf.Write(d.src[b:])
+	doc, err := Parse(buf.Bytes(), d.opts...)
+	if err != nil {
+		return err
+	}
+	*d = *doc
+	return nil
+}
+
+// lookup returns the member or array value at path, and its value. The
+// member is nil for the top level object.
+func (d *Document) lookup(path []string) (Node, Node, error) {
+	var item Node
+	n := d.Root
+	for i := range path {
+		var err error
+		if item, n, err = d.child(n, path[:i+1]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return item, n, nil
+}
+
+// child returns the member or array value of n designated by the last
+// element of path, and its value.
+func (d *Document) child(n Node, path []string) (Node, Node, error) {
+	name := path[len(path)-1]
+	switch x := n.(type) {
+	case *Object:
+		var found *Member
+		for _, m := range x.Members {
+			if m.Name.Value == name {
+				found = m
+			}
+		}
+		if found != nil {
+			return found, found.Value, nil
+		}
+	case *Array:
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < len(x.Values) {
+			return x.Values[i], x.Values[i], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrPathNotFound, strings.Join(path, "."))
+}
+
+// comma returns the offset of the comma between offsets b and e, or -1.
+func (d *Document) comma(b, e int) int {
+	s := NewScanner(d.src[b:e], 0)
+	for t := s.Scan(); t.Kind != TokenEOF && t.Kind != TokenError; t = s.Scan() {
+		if t.Kind == TokenComma {
+			return b + t.Pos.Offset
+		}
+	}
+	return -1
+}
+
+// restOfLine returns the offset following the newline that ends the line
+// of offset b when it is followed only by whitespaces, the comma at offset
+// comma and comments. It returns -1 otherwise.
+func (d *Document) restOfLine(b, comma int) int {
+	s := NewScanner(d.src[b:], ScanComments|ScanWhitespaces)
+	for {
+		t := s.Scan()
+		switch {
+		case t.Kind == TokenNewline:
+			return b + t.Pos.Offset + len(t.Raw)
+		case t.Kind == TokenEOF:
+			return len(d.src)
+		case t.Kind == TokenWhitespace,
+			t.Kind == TokenComment && bytes.IndexByte(t.Raw, '\n') < 0,
+			t.Kind == TokenComma && b+t.Pos.Offset == comma:
+		default:
+			return -1
+		}
+	}
+}
+
+// lineStart returns the offset of the start of the line of offset i.
+func (d *Document) lineStart(i int) int {
+	return bytes.LastIndexByte(d.src[:i], '\n') + 1
+}
+
+// indent returns the whitespaces at the start of the line of offset i.
+func (d *Document) indent(i int) string {
+	b := d.lineStart(i)
+	e := b
+	for w := whitespace(d.src[e:i]); w != 0; w = whitespace(d.src[e:i]) {
+		e += w
+	}
+	return string(d.src[b:e])
+}
+
+// reindent inserts indent at the start of the lines of s following its
+// first line.
+func reindent(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// checkValue returns an error if value is not the text of a single value.
+func checkValue(value string) error {
+	doc, err := Parse([]byte("v:\n" + value))
+	if err == nil && len(doc.Root.(*Object).Members) == 1 && value != "" {
+		return nil
+	}
+	return fmt.Errorf("%w %q", ErrInvalidValue, value)
+}
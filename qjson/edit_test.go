@@ -0,0 +1,101 @@
+package qjson
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrint(t *testing.T) {
+	tests := []string{
+		// 0
+		"",
+		"# header\r\na : 1 ,b:'x' // c\n\n  c: {d: [1, 2,3]}\n",
+		"a:\n  `\\r\\n # text\n  line\n  `   \nb: /* c */ \"d\"",
+		"a: 1\t/* x\ny */\t\n",
+	}
+	for i, in := range tests {
+		doc, err := Parse([]byte(in), WithComments())
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if out := string(doc.Print()); out != in {
+			t.Fatalf("%d expected %q, got %q", i, in, out)
+		}
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tests := []struct {
+		in    string
+		op    string
+		path  string
+		key   string
+		value string
+		out   string
+		err   string
+	}{
+		// 0
+		{in: "version: '1.2.3' # current\nname: app\n", op: "set", path: "version", value: "'1.2.4'", out: "version: '1.2.4' # current\nname: app\n"},
+		{in: "a: {b: [1, 2, 3]}", op: "set", path: "a.b.1", value: "'two'", out: "a: {b: [1, 'two', 3]}"},
+		{in: "a: 1\na: 2\n", op: "set", path: "a", value: "3", out: "a: 1\na: 3\n"},
+		{in: "a: {\n  b: 1\n}", op: "set", path: "a.b", value: "{\nc: 2\n}", out: "a: {\n  b: {\n  c: 2\n  }\n}"},
+		{in: "a: {\n  b: 1\n}", op: "set", path: "a.b", value: "`\\n\nx\n`", out: "a: {\n  b:\n    `\\n\n    x\n    `\n}"},
+		// 5
+		{in: "a: 1", op: "set", path: "b", value: "2", err: "path not found: b"},
+		{in: "a: [1]", op: "set", path: "a.1", value: "2", err: "path not found: a.1"},
+		{in: "a: 1", op: "set", path: "a", value: "2\nb: 3", err: "invalid value \"2\\nb: 3\""},
+		{in: "a: 1", op: "set", path: "", value: "2", err: "path not found: empty path"},
+		{in: "# a\na: 1 # one\nb: 2\n", op: "delete", path: "a", out: "# a\nb: 2\n"},
+		// 10
+		{in: "a: 1,\nb: 2 # two\n", op: "delete", path: "b", out: "a: 1\n"},
+		{in: "a: 1,\nb: 2,\nc: 3\n", op: "delete", path: "b", out: "a: 1,\nc: 3\n"},
+		{in: "a: {x: 1, y: 2}", op: "delete", path: "a.x", out: "a: {y: 2}"},
+		{in: "a: {x: 1, y: 2}", op: "delete", path: "a.y", out: "a: {x: 1}"},
+		{in: "a: [1, 2, 3]", op: "delete", path: "a.1", out: "a: [1, 3]"},
+		// 15
+		{in: "a: {x: 1}", op: "delete", path: "a.x", out: "a: {}"},
+		{in: "a: 1", op: "delete", path: "a.b", err: "path not found: a.b"},
+		{in: "a: 1 # one\r\n", op: "insert", key: "b", value: "2", out: "a: 1 # one\r\nb: 2\r\n"},
+		{in: "a: {\n  x: 1\n}", op: "insert", path: "a", key: "my key", value: "[1, 2]", out: "a: {\n  x: 1\n  my key: [1, 2]\n}"},
+		{in: "a: {x: 1}", op: "insert", path: "a", key: "y:z", value: "2", out: "a: {x: 1, \"y:z\": 2}"},
+		// 20
+		{in: "a: {}", op: "insert", path: "a", key: "x", value: "1", out: "a: {x: 1}"},
+		{in: "a: {\n}", op: "insert", path: "a", key: "x", value: "1", out: "a: {\n  x: 1\n}"},
+		{in: "# empty", op: "insert", key: "x", value: "1", out: "# empty\nx: 1\n"},
+		{in: "a: 1", op: "insert", key: "t", value: "`\\n\nx\n`", out: "a: 1\nt:\n  `\\n\n  x\n  `"},
+		{in: "a: 1", op: "insert", path: "a", key: "x", value: "1", err: "not an object: a"},
+	}
+	for i, test := range tests {
+		doc, err := Parse([]byte(test.in), WithComments())
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		var path []string
+		if test.path != "" {
+			path = strings.Split(test.path, ".")
+		}
+		switch test.op {
+		case "set":
+			err = doc.SetValue(path, test.value)
+		case "delete":
+			err = doc.DeleteMember(path)
+		case "insert":
+			err = doc.InsertMember(path, test.key, test.value)
+		}
+		if test.err != "" {
+			if e2s(err) != test.err {
+				t.Fatalf("%d expected error %q, got %q", i, test.err, e2s(err))
+			}
+			if out := string(doc.Print()); out != test.in {
+				t.Fatalf("%d expected unchanged text, got %q", i, out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d unexpected error: %v", i, err)
+		}
+		if out := string(doc.Print()); out != test.out {
+			t.Fatalf("%d expected %q, got %q", i, test.out, out)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package qjson
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
-	"strconv"
+	"io"
+	"sort"
 )
 
 // Version returns the version of the code and the supported
@@ -14,38 +16,331 @@ func Version() string {
 
 // Decode accept QJSON text as input and return a JSON text or return an error.
 func Decode(input []byte) ([]byte, error) {
+	return DecodeWithOptions(input, DecodeOptions{})
+}
+
+// DecodeWithOptions is like Decode with the decoding options opts.
+func DecodeWithOptions(input []byte, opts DecodeOptions) ([]byte, error) {
 	if input == nil {
 		return []byte("{}"), nil
 	}
 	var e engine
+	e.opts = opts
 	e.init(input)
-	e.members()
-	if e.token().tag == tagCloseBrace {
-		e.setError(ErrUnexpectedCloseBrace)
-	}
-	t := e.token()
-	if t.tag == tagError && t.val.(error) != ErrEndOfInput {
-		return nil, fmt.Errorf("%w at line %d col %d", t.val.(error), t.pos.l+1, column(e.in[t.pos.s:t.pos.b])+1)
+	e.document()
+	if err := e.err(); err != nil {
+		return nil, err
 	}
 	return e.out.Bytes(), nil
 }
 
-var maxDepth = 200
+// DecodeAll is like Decode but reports all the syntax errors of the QJSON
+// text instead of the first one. After a syntax error, the decoding
+// resumes at the following newline, comma or closing brace or square
+// bracket. The error is an ErrorList when syntax errors are found.
+func DecodeAll(input []byte) ([]byte, error) {
+	if input == nil {
+		return []byte("{}"), nil
+	}
+	var e engine
+	e.allErrors = true
+	e.init(input)
+	e.document()
+	if err := e.errList(); err != nil {
+		return nil, err
+	}
+	return e.out.Bytes(), nil
+}
 
-// engine to convert QJSON to JSON.
+// engine to convert QJSON to JSON. The values are passed to the builder
+// b which outputs JSON text into out by default.
 type engine struct {
 	tokenizer
 	depth int
 	out   bytes.Buffer
+	b     builder
+
+	opts      DecodeOptions // decoding options, kept by init
+	allErrors bool          // resume after syntax errors, kept by init
+	errs      ErrorList     // syntax errors met before the current token
+	resumed   pos           // position where the decoding was last resumed
+
+	keys   []map[string]Pos // member names of the open objects, if checked
+	dupKey bool             // the current member name is a duplicate
+	dups   bool             // a duplicate member name was met
+}
+
+// A builder receives the values recognized by the engine. The token of the
+// value is the current token of the engine. A builder reports an error with
+// e.setError.
+type builder interface {
+	openObject(e *engine)
+	closeObject(e *engine)
+	openArray(e *engine)
+	closeArray(e *engine)
+	memberName(e *engine)
+	stringValue(e *engine)
+	literalValue(e *engine, lit string)
+	numberValue(e *engine, num string)
+}
+
+// jsonBuilder outputs the values as JSON text in e.out. When w is not
+// nil, the JSON text is flushed to w as it is produced.
+type jsonBuilder struct {
+	comma bool      // a comma must precede the next value or member
+	w     io.Writer // destination of the JSON text, or nil
+	err   error     // error returned by w
+	lvl   int       // number of open objects and arrays
+}
+
+// flushSize is the size of the JSON text above which it is flushed to w.
+const flushSize = 32 * 1024
+
+func (b *jsonBuilder) separate(e *engine) {
+	if b.comma {
+		e.out.WriteByte(',')
+	}
+	// the JSON text is kept until the end when duplicates are removed
+	if b.w != nil && e.out.Len() >= flushSize && !e.opts.Duplicates.removes() {
+		b.flush(e)
+	}
+}
+
+// flush writes the JSON text in e.out to w.
+func (b *jsonBuilder) flush(e *engine) {
+	if _, err := b.w.Write(e.out.Bytes()); err != nil {
+		b.err = err
+		e.setError(err)
+	}
+	e.out.Reset()
+}
+
+func (b *jsonBuilder) openObject(e *engine) {
+	b.separate(e)
+	e.out.WriteByte('{')
+	b.comma = false
+	b.lvl++
+}
+
+func (b *jsonBuilder) closeObject(e *engine) {
+	e.out.WriteByte('}')
+	b.comma = true
+	b.close(e)
+}
+
+// close is called when an object or array is closed. The duplicates are
+// removed when the top level value is closed.
+func (b *jsonBuilder) close(e *engine) {
+	b.lvl--
+	if b.lvl == 0 && e.dups && e.opts.Duplicates.removes() {
+		out := removeDuplicates(e.out.Bytes(), e.opts.Duplicates)
+		e.out.Reset()
+		e.out.Write(out)
+	}
+}
+
+func (b *jsonBuilder) openArray(e *engine) {
+	b.separate(e)
+	e.out.WriteByte('[')
+	b.comma = false
+	b.lvl++
+}
+
+func (b *jsonBuilder) closeArray(e *engine) {
+	e.out.WriteByte(']')
+	b.comma = true
+	b.close(e)
+}
+
+func (b *jsonBuilder) memberName(e *engine) {
+	b.separate(e)
+	e.outputString()
+	e.out.WriteByte(':')
+	b.comma = false
+}
+
+func (b *jsonBuilder) stringValue(e *engine) {
+	b.separate(e)
+	e.outputString()
+	b.comma = true
+}
+
+func (b *jsonBuilder) literalValue(e *engine, lit string) {
+	b.separate(e)
+	e.out.WriteString(lit)
+	b.comma = true
+}
+
+func (b *jsonBuilder) numberValue(e *engine, num string) {
+	b.separate(e)
+	e.out.WriteString(num)
+	b.comma = true
 }
 
 func (e *engine) init(input []byte) {
 	e.tokenizer.init(input)
+	e.reset()
+}
+
+// initReader initializes the engine to read its input text from r.
+func (e *engine) initReader(r io.Reader) {
+	e.tokenizer.initReader(r)
+	e.reset()
+}
+
+func (e *engine) reset() {
 	e.out.Reset()
 	e.depth = 0
+	e.errs = nil
+	e.resumed = pos{}
+	e.keys = e.keys[:0]
+	e.dupKey, e.dups = false, false
+	e.b = &jsonBuilder{}
+	e.isExpr = e.opts.isNumberExpr
 	e.nextToken()
 }
 
+// document process the members of the implicit top level object, or the
+// root value.
+func (e *engine) document() {
+	if e.opts.Root == RootValue || e.opts.Root == RootDefault && e.tk.tag == tagOpenSquare {
+		e.rootValue()
+		return
+	}
+	e.b.openObject(e)
+	e.members()
+	for e.tk.tag == tagCloseBrace {
+		e.setError(ErrUnexpectedCloseBrace)
+		if e.resync(tagCloseBrace) {
+			e.members()
+		}
+	}
+	if e.tk.tag == tagError && e.tk.val.(error) == ErrEndOfInput {
+		e.b.closeObject(e)
+	}
+}
+
+// rootValue process the root value that must be the only value of the
+// input.
+func (e *engine) rootValue() {
+	if e.done() {
+		if e.tk.val.(error) == ErrEndOfInput {
+			e.setError(ErrUnexpectedEndOfInput)
+		}
+		return
+	}
+	e.value()
+	if !e.done() {
+		e.setError(ErrValueAfterRoot)
+	}
+}
+
+// err returns the error met by the engine, or nil if the input was
+// processed successfully.
+func (e *engine) err() error {
+	t := e.token()
+	if t.tag != tagError || t.val.(error) == ErrEndOfInput {
+		return nil
+	}
+	if err, ok := t.val.(*UnmarshalTypeError); ok {
+		err.Pos = e.position(t.pos)
+		return err
+	}
+	if err, ok := t.val.(*DuplicateKeyError); ok {
+		err.Pos = e.position(t.pos)
+		return err
+	}
+	p := e.position(t.pos)
+	if code, ok := t.val.(Error); ok {
+		return &SyntaxError{Code: code, Pos: p}
+	}
+	return fmt.Errorf("%w at line %d col %d", t.val.(error), p.Line, p.Column)
+}
+
+// errList returns the error met by the engine like err. When all errors
+// are reported, the syntax errors are returned sorted by offset as an
+// ErrorList.
+func (e *engine) errList() error {
+	err := e.err()
+	if !e.allErrors {
+		return err
+	}
+	se, ok := err.(*SyntaxError)
+	if err != nil && !ok {
+		return err
+	}
+	list := e.errs
+	if se != nil {
+		list = append(list, se)
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Offset < list[j].Offset })
+	return list
+}
+
+// resume returns true if the current token is not an error. When all errors
+// are reported, the syntax errors are recorded and skipped with resync.
+func (e *engine) resume(skip tokenTag) bool {
+	for e.done() {
+		if !e.resync(skip) {
+			return false
+		}
+	}
+	return true
+}
+
+// resync records the syntax error of the current token and moves to the
+// next token following the error at the next newline, comma, closing brace
+// or closing square bracket. A skip closing brace or square bracket at the
+// error position is skipped. An error at or before the last recorded error
+// is not recorded again. When the error precedes the position where the
+// decoding was last resumed, the search starts after this position. It
+// returns false, leaving the error token
+// unchanged, when errors are not recovered, the error is not a syntax error
+// or the end of input is reached.
+func (e *engine) resync(skip tokenTag) bool {
+	if !e.allErrors || e.r != nil || !e.done() || len(e.p) == 0 {
+		return false
+	}
+	code, ok := e.tk.val.(Error)
+	if !ok || code == ErrEndOfInput || code == ErrMaxObjectArrayDepth {
+		return false
+	}
+	e.pos = e.tk.pos
+	n := len(e.errs)
+	reported := n != 0 && e.pos.b <= e.errs[n-1].Offset
+	if !reported {
+		e.errs = append(e.errs, &SyntaxError{Code: code, Pos: e.position(e.pos)})
+	}
+	if e.pos.b < e.resumed.b || e.pos.b == e.resumed.b && reported {
+		// ensure progress when the error precedes the resumed position
+		e.pos = e.resumed
+		e.p = e.in[e.pos.b-e.off:]
+		if !e.popNewline() {
+			e.popBytes(1)
+		}
+	} else {
+		e.p = e.in[e.pos.b-e.off:]
+		if len(e.p) != 0 && (skip == tagCloseBrace && e.p[0] == '}' || skip == tagCloseSquare && e.p[0] == ']') {
+			e.popBytes(1)
+		}
+	}
+	for len(e.p) != 0 && !e.popNewline() && e.p[0] != ',' && e.p[0] != '}' && e.p[0] != ']' {
+		e.popBytes(1)
+	}
+	e.resumed = e.pos
+	e.tk = token{}
+	e.nextToken()
+	return true
+}
+
+// disabled returns true if the extension x is disabled.
+func (e *engine) disabled(x Extension) bool {
+	return e.opts.Disabled&x != 0
+}
+
 func (e *engine) done() bool {
 	return e.tk.tag == tagError
 }
@@ -57,6 +352,17 @@ func (e *engine) setErrorAndPos(err error, p pos) {
 	e.tk = token{tag: tagError, pos: p, val: err}
 }
 
+// numberWarning reports the warning msg of the number expression of the
+// current token at its index i.
+func (e *engine) numberWarning(msg string, i int) {
+	if e.opts.Warn == nil {
+		return
+	}
+	p := e.tk.pos
+	p.b += i
+	e.opts.Warn(Warning{Msg: msg, Text: string(e.tk.val.([]byte)), Pos: e.position(p)})
+}
+
 // value process a value. If an error occurred it returns with the error set,
 // otherwise calls nextToken() and return its result.
 func (e *engine) value() bool {
@@ -67,38 +373,59 @@ func (e *engine) value() bool {
 	case tagCloseBrace:
 		e.setError(ErrUnexpectedCloseBrace)
 		return false
-	case tagDoubleQuotedString:
-		e.outputDoubleQuotedString()
 	case tagSingleQuotedString:
-		e.outputSingleQuotedString()
-	case tagMultilineString:
-		e.outputMultilineString()
+		if e.disabled(ExtSingleQuotes) {
+			e.setError(ErrSingleQuotedStringDisabled)
+			return true
+		}
+		e.b.stringValue(e)
+	case tagDoubleQuotedString, tagMultilineString:
+		e.b.stringValue(e)
 	case tagQuotelessString:
 		val := e.tk.val.([]byte)
-		if str := isLiteralValue(val); str != "" {
-			e.out.WriteString(str)
-		} else if isNumberExpr(val) {
-			res, pos, err := evalNumberExpression(val)
-			if err != nil {
+		if str := e.opts.literal(val); str != "" {
+			if e.disabled(ExtAlternateLiterals) && isAlternateLiteral(val, str) {
+				e.setError(ErrAlternateLiteralDisabled)
+				return true
+			}
+			if e.opts.Warn != nil && isAlternateLiteral(val, str) {
+				e.opts.Warn(Warning{Msg: "quoteless " + string(val) + " decoded as " + str,
+					Text: string(val), Pos: e.position(e.tk.pos)})
+			}
+			e.b.literalValue(e, str)
+		} else if e.opts.KeepNumberLiterals && isJSONNumber(val) {
+			e.b.numberValue(e, string(val))
+		} else if e.opts.isNumberExpr(val) {
+			res, pos, err := evalNumberExpression(val, &e.opts, e.numberWarning)
+			switch {
+			case err != nil && !e.disabled(ExtQuotelessStrings) && isQuotelessText(val, err):
+				// like round(ish) estimate
+				e.b.stringValue(e)
+			case err != nil:
 				p := e.tk.pos
 				p.b += pos
 				e.setErrorAndPos(err, p)
 				return true
+			default:
+				e.b.numberValue(e, formatNumber(res))
 			}
-			e.out.WriteString(strconv.FormatFloat(res, 'g', 16, 64))
+		} else if e.disabled(ExtQuotelessStrings) {
+			e.setError(ErrQuotelessStringDisabled)
+			return true
 		} else {
-			e.outputQuotelessString()
+			e.b.stringValue(e)
 		}
 	case tagOpenBrace:
-		startPos := e.tk.pos
+		startPos := e.keepColumn(e.tk.pos)
+		e.b.openObject(e)
 		e.nextToken()
-		if e.done() {
+		if !e.resume(tagCloseSquare) {
 			if e.tk.val.(error) == ErrEndOfInput {
 				e.setErrorAndPos(ErrUnclosedObject, startPos)
 			}
 			return true
 		}
-		if e.depth == maxDepth {
+		if e.depth == e.opts.maxDepth() {
 			e.setError(ErrMaxObjectArrayDepth)
 			return true
 		}
@@ -110,16 +437,18 @@ func (e *engine) value() bool {
 			return true
 		}
 		e.depth--
+		e.b.closeObject(e)
 	case tagOpenSquare:
+		e.b.openArray(e)
 		e.nextToken()
-		if e.done() {
+		if !e.resume(tagCloseBrace) {
 			if e.tk.val.(error) == ErrEndOfInput {
 				e.setError(ErrUnclosedArray)
 			}
 			return true
 		}
-		startPos := e.tk.pos
-		if e.depth == maxDepth {
+		startPos := e.keepColumn(e.tk.pos)
+		if e.depth == e.opts.maxDepth() {
 			e.setError(ErrMaxObjectArrayDepth)
 			return true
 		}
@@ -131,6 +460,7 @@ func (e *engine) value() bool {
 			return true
 		}
 		e.depth--
+		e.b.closeArray(e)
 	default:
 		e.setError(ErrSyntaxError)
 		//		e.setError(fmt.Errorf("expected value, got %v", e.tk))
@@ -143,31 +473,29 @@ func (e *engine) value() bool {
 // values process 0 or more values and pops the ending ]. Return done().
 func (e *engine) values() bool {
 	var notFirst bool
-	e.out.WriteByte('[')
-	for !e.done() && e.tk.tag != tagCloseSquare {
+	for e.resume(tagCloseBrace) && e.tk.tag != tagCloseSquare {
 		if notFirst {
-			e.out.WriteByte(',')
 			if e.tk.tag == tagComma {
 				e.nextToken()
 				if e.done() {
 					if e.tk.val.(error) == ErrEndOfInput {
 						e.setError(ErrExpectValueAfterComma)
 					}
-					break
+					continue
 				}
 				if e.tk.tag == tagCloseBrace || e.tk.tag == tagCloseSquare {
 					e.setError(ErrExpectValueAfterComma)
-					break
+					continue
 				}
+			} else if e.disabled(ExtOptionalCommas) {
+				e.setError(ErrMissingComma)
+				continue
 			}
 		} else {
 			notFirst = true
 		}
-		if e.value() {
-			break
-		}
+		e.value()
 	}
-	e.out.WriteByte(']')
 	return e.done()
 }
 
@@ -176,12 +504,20 @@ func (e *engine) member() bool {
 	case tagCloseSquare:
 		e.setError(ErrUnexpectedCloseSquare)
 		return false
-	case tagDoubleQuotedString:
-		e.outputDoubleQuotedString()
 	case tagSingleQuotedString:
-		e.outputSingleQuotedString()
-	case tagQuotelessString:
-		e.outputQuotelessString()
+		if e.disabled(ExtSingleQuotes) {
+			e.setError(ErrSingleQuotedStringDisabled)
+			return true
+		}
+		if !e.checkKey() {
+			return true
+		}
+		e.b.memberName(e)
+	case tagDoubleQuotedString, tagQuotelessString:
+		if !e.checkKey() {
+			return true
+		}
+		e.b.memberName(e)
 	default:
 		e.setError(ErrExpectStringIdentifier)
 	}
@@ -196,7 +532,6 @@ func (e *engine) member() bool {
 		e.setError(ErrExpectColon)
 		return true
 	}
-	e.out.WriteByte(':')
 	e.nextToken()
 	if e.done() {
 		if e.tk.val.(error) == ErrEndOfInput {
@@ -207,34 +542,63 @@ func (e *engine) member() bool {
 	return e.value()
 }
 
+// checkKey records the name of the current member token in the names of
+// its object when duplicate names are checked, and sets dupKey. It returns
+// false with the error set when the name is invalid or is a duplicate
+// reported as an error.
+func (e *engine) checkKey() bool {
+	e.dupKey = false
+	if e.opts.Duplicates == DuplicateKeep {
+		return true
+	}
+	name, ok := e.stringValue()
+	if !ok {
+		return false
+	}
+	keys := e.keys[len(e.keys)-1]
+	first, ok := keys[name]
+	if !ok {
+		keys[name] = e.position(e.tk.pos)
+		return true
+	}
+	if e.opts.Duplicates == DuplicateError {
+		e.setError(&DuplicateKeyError{Key: name, First: first})
+		return false
+	}
+	e.dupKey, e.dups = true, true
+	return true
+}
+
 // values process 0 or more members (identifiers : value) and pops the ending }. Return done().
 func (e *engine) members() bool {
+	if e.opts.Duplicates != DuplicateKeep {
+		e.keys = append(e.keys, map[string]Pos{})
+		defer func() { e.keys = e.keys[:len(e.keys)-1] }()
+	}
 	var notFirst bool
-	e.out.WriteByte('{')
-	for !e.done() && e.tk.tag != tagCloseBrace {
+	for e.resume(tagCloseSquare) && e.tk.tag != tagCloseBrace {
 		if notFirst {
-			e.out.WriteByte(',')
 			if e.tk.tag == tagComma {
 				e.nextToken()
 				if e.done() {
 					if e.tk.val.(error) == ErrEndOfInput {
 						e.setError(ErrExpectIdentifierAfterComma)
 					}
-					break
+					continue
 				}
 				if e.tk.tag == tagCloseBrace || e.tk.tag == tagCloseSquare {
 					e.setError(ErrExpectIdentifierAfterComma)
-					break
+					continue
 				}
+			} else if e.disabled(ExtOptionalCommas) {
+				e.setError(ErrMissingComma)
+				continue
 			}
 		} else {
 			notFirst = true
 		}
-		if e.member() {
-			break
-		}
+		e.member()
 	}
-	e.out.WriteByte('}')
 	return e.done()
 }
 
@@ -276,6 +640,38 @@ func isLiteralValue(p []byte) string {
 	return ""
 }
 
+// outputString outputs the string token as a JSON string.
+func (e *engine) outputString() {
+	switch e.tk.tag {
+	case tagDoubleQuotedString:
+		e.outputDoubleQuotedString()
+	case tagSingleQuotedString:
+		e.outputSingleQuotedString()
+	case tagMultilineString:
+		e.outputMultilineString()
+	default:
+		e.outputQuotelessString()
+	}
+}
+
+// stringValue returns the value of the string token. It uses the end of
+// out as scratch buffer. It returns false when the string is invalid in
+// which case the error is set.
+func (e *engine) stringValue() (string, bool) {
+	n := e.out.Len()
+	defer e.out.Truncate(n)
+	e.outputString()
+	if e.done() {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(e.out.Bytes()[n:], &s); err != nil {
+		e.setError(ErrInvalidEscapeSequence)
+		return "", false
+	}
+	return s, true
+}
+
 func (e *engine) outputDoubleQuotedString() {
 	str := e.tk.val.([]byte)
 	e.out.WriteByte('"')
@@ -292,12 +688,17 @@ func (e *engine) outputDoubleQuotedString() {
 		case '\\':
 			c := str[i+1]
 			if c != 't' && c != 'n' && c != 'r' && c != 'f' && c != 'b' && c != '/' && c != '\\' && c != '"' &&
-				!(c == 'u' && len(str) >= i+6 && isHexDigit(str[i+2]) && isHexDigit(str[i+3]) && isHexDigit(str[i+5]) && isHexDigit(str[i+5])) {
+				!(c == 'u' && len(str) >= i+6 && isHexDigit(str[i+2]) && isHexDigit(str[i+3]) && isHexDigit(str[i+4]) && isHexDigit(str[i+5])) {
 				p := e.tk.pos
 				p.b += i
 				e.setErrorAndPos(ErrInvalidEscapeSequence, p)
 				return
 			}
+			// the escaped character is written with its backslash
+			e.out.WriteByte('\\')
+			e.out.WriteByte(c)
+			i++
+			continue
 		}
 		e.out.WriteByte(str[i])
 	}
@@ -320,15 +721,20 @@ func (e *engine) outputSingleQuotedString() {
 		case '\\':
 			c := str[i+1]
 			if c != 't' && c != 'n' && c != 'r' && c != 'f' && c != 'b' && c != '/' && c != '\\' && c != '\'' &&
-				!(c == 'u' && len(str) >= i+6 && isHexDigit(str[i+2]) && isHexDigit(str[i+3]) && isHexDigit(str[i+5]) && isHexDigit(str[i+5])) {
+				!(c == 'u' && len(str) >= i+6 && isHexDigit(str[i+2]) && isHexDigit(str[i+3]) && isHexDigit(str[i+4]) && isHexDigit(str[i+5])) {
 				p := e.tk.pos
 				p.b += i
 				e.setErrorAndPos(ErrInvalidEscapeSequence, p)
 				return
 			}
-			if c == '\'' {
-				continue
+			// the escaped character is written with its backslash, except a
+			// single quote
+			if c != '\'' {
+				e.out.WriteByte('\\')
 			}
+			e.out.WriteByte(c)
+			i++
+			continue
 		case '"':
 			e.out.WriteByte('\\')
 		}
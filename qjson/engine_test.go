@@ -3,6 +3,7 @@ package qjson
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -256,13 +257,52 @@ func TestDecode(t *testing.T) {
 		t.Fatalf("expect out: %s err: %s, got out: %s err: %s", "\"{}\"", "nil", b2q(out), errStr(err))
 	}
 
-	maxDepth = 3
-	if out, err := Decode([]byte("a:[[[[]]]]")); b2q(out) != "nil" || errStr(err) != "error: too many object or array encapsulations at line 1 col 7" {
+	opts := DecodeOptions{MaxDepth: 3}
+	if out, err := DecodeWithOptions([]byte("a:[[[[]]]]"), opts); b2q(out) != "nil" || errStr(err) != "error: too many object or array encapsulations at line 1 col 7" {
 		t.Fatalf("expect out: %s err: %s, got out: %s err: %s", "\"{}\"", "nil", b2q(out), errStr(err))
 	}
-	if out, err := Decode([]byte("a:{b:{c:{d:{}}}}}")); b2q(out) != "nil" || errStr(err) != "error: too many object or array encapsulations at line 1 col 13" {
+	if out, err := DecodeWithOptions([]byte("a:{b:{c:{d:{}}}}}"), opts); b2q(out) != "nil" || errStr(err) != "error: too many object or array encapsulations at line 1 col 13" {
 		t.Fatalf("expect out: %s err: %s, got out: %s err: %s", "\"{}\"", "nil", b2q(out), errStr(err))
 	}
-	maxDepth = 200
+}
 
+func TestDecodeAll(t *testing.T) {
+	tests := []struct {
+		in   string
+		out  string
+		errs []string
+	}{
+		// 0
+		{in: "a: 1\nb: [c, d]", out: "{\"a\":1,\"b\":[\"c\",\"d\"]}"},
+		{in: "a: {", errs: []string{"unclosed object at line 1 col 4"}},
+		{in: "a: 'x\nb: 1/0\nc: 3", errs: []string{"newline in single quoted string at line 1 col 4", "division by zero at line 2 col 5"}},
+		{in: "a: {b: ]}\nc: [1, }, 2]\n: d", errs: []string{"unexpected ] at line 1 col 8", "expect value after comma at line 2 col 8", "expect string identifier at line 3 col 1"}},
+		{in: "a: [1, ]\nb: {c: 1, }\nd: [,]\ne: {,}", errs: []string{"expect value after comma at line 1 col 8", "expect identifier after comma at line 2 col 11",
+			"syntax error at line 3 col 5", "expect value after comma at line 3 col 6", "expect string identifier at line 4 col 5", "expect identifier after comma at line 4 col 6"}},
+		// 5
+		{in: "] a: 1\n}\nb", errs: []string{"unexpected ] at line 1 col 1", "unexpected } at line 2 col 1", "unexpected end of input at line 3 col 2"}},
+		{in: "a: [{'x\n]\nb: 1", errs: []string{"unclosed object at line 1 col 5", "newline in single quoted string at line 1 col 6", "unexpected ] at line 2 col 1"}},
+		{in: "a: 1, `x\nb: 2, `y", errs: []string{"multiline margin must contain only whitespaces at line 1 col 1", "multiline margin must contain only whitespaces at line 2 col 1"}},
+	}
+	for i, test := range tests {
+		out, err := DecodeAll([]byte(test.in))
+		if test.errs == nil {
+			if err != nil || string(out) != test.out {
+				t.Fatalf("%d expected %q, got %q %v", i, test.out, out, err)
+			}
+			continue
+		}
+		var errs []string
+		if l, ok := err.(ErrorList); ok {
+			for _, e := range l {
+				errs = append(errs, e.Error())
+			}
+		}
+		if out != nil || !reflect.DeepEqual(errs, test.errs) {
+			t.Fatalf("%d expected errors %q, got %q %v", i, test.errs, errs, err)
+		}
+	}
+	if exp := "unexpected ] at line 1 col 1 (and 2 more errors)"; e2s(ErrorList{{Code: ErrUnexpectedCloseSquare, Pos: Pos{Line: 1, Column: 1}}, nil, nil}) != exp {
+		t.Fatalf("expected %q", exp)
+	}
 }
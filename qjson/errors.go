@@ -16,6 +16,48 @@ type Error string
 
 func (e Error) Error() string { return string(e) }
 
+// A SyntaxError is an error in a QJSON text. Its code is one of the Err
+// constants, and its position is the location of the error in the text.
+type SyntaxError struct {
+	Code Error // the error, one of the Err constants
+	Pos        // position of the error in the QJSON text
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Code.Error() + " at " + e.Pos.String()
+}
+
+// Unwrap returns the code of the error.
+func (e *SyntaxError) Unwrap() error { return e.Code }
+
+// An ErrorList is a list of syntax errors sorted by offset.
+type ErrorList []*SyntaxError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// A DuplicateKeyError is returned when a member name is repeated in an
+// object and duplicates are errors.
+type DuplicateKeyError struct {
+	Key   string // the unquoted member name
+	Pos          // position of the repeated member name
+	First Pos    // position of the first member name
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("%s %q at %s, first at %s", ErrDuplicateKey, e.Key, e.Pos, e.First)
+}
+
+// Unwrap returns ErrDuplicateKey.
+func (e *DuplicateKeyError) Unwrap() error { return ErrDuplicateKey }
+
 var errMap = map[Error]string{
 	ErrEndOfInput:                 "ErrEndOfInput",
 	ErrInvalidChar:                "ErrInvalidChar",
@@ -57,6 +99,25 @@ var errMap = map[Error]string{
 	ErrUnexpectedCloseBrace:       "ErrUnexpectedCloseBrace",
 	ErrUnexpectedCloseSquare:      "ErrUnexpectedCloseSquare",
 	ErrInvalidISODateTime:         "ErrInvalidISODateTime",
+	ErrPathNotFound:               "ErrPathNotFound",
+	ErrNotAnObject:                "ErrNotAnObject",
+	ErrInvalidValue:               "ErrInvalidValue",
+	ErrAlternateLiteralDisabled:   "ErrAlternateLiteralDisabled",
+	ErrNumberExpressionDisabled:   "ErrNumberExpressionDisabled",
+	ErrDurationDisabled:           "ErrDurationDisabled",
+	ErrISODateTimeDisabled:        "ErrISODateTimeDisabled",
+	ErrQuotelessStringDisabled:    "ErrQuotelessStringDisabled",
+	ErrSingleQuotedStringDisabled: "ErrSingleQuotedStringDisabled",
+	ErrMissingComma:               "ErrMissingComma",
+	ErrDuplicateKey:               "ErrDuplicateKey",
+	ErrValueAfterRoot:             "ErrValueAfterRoot",
+	ErrInexactDecimal:             "ErrInexactDecimal",
+	ErrNegativeShiftCount:         "ErrNegativeShiftCount",
+	ErrUnknownFunction:            "ErrUnknownFunction",
+	ErrUnknownConstant:            "ErrUnknownConstant",
+	ErrInvalidArgumentCount:       "ErrInvalidArgumentCount",
+	ErrNotANumber:                 "ErrNotANumber",
+	ErrFractionalSize:             "ErrFractionalSize",
 }
 
 func errStr(e error) string {
@@ -191,3 +252,60 @@ const ErrUnexpectedCloseSquare = Error("unexpected ]")
 
 // ErrInvalidISODateTime is returned when the parsed ISO date time is invalid.
 const ErrInvalidISODateTime = Error("invalid ISO date time")
+
+// ErrPathNotFound is returned when a document edit refers to a missing member or value.
+const ErrPathNotFound = Error("path not found")
+
+// ErrNotAnObject is returned when a member is inserted in a value that is not an object.
+const ErrNotAnObject = Error("not an object")
+
+// ErrInvalidValue is returned when the text of a document edit is not a single value.
+const ErrInvalidValue = Error("invalid value")
+
+// ErrAlternateLiteralDisabled is returned when an alternate literal, like yes, no, on or off, is met while disabled.
+const ErrAlternateLiteralDisabled = Error("alternate literals are disabled")
+
+// ErrNumberExpressionDisabled is returned when a numeric expression is met while disabled.
+const ErrNumberExpressionDisabled = Error("numeric expressions are disabled")
+
+// ErrDurationDisabled is returned when a duration is met while disabled.
+const ErrDurationDisabled = Error("durations are disabled")
+
+// ErrISODateTimeDisabled is returned when an ISO date time is met while disabled.
+const ErrISODateTimeDisabled = Error("ISO date times are disabled")
+
+// ErrQuotelessStringDisabled is returned when a quoteless string value is met while disabled.
+const ErrQuotelessStringDisabled = Error("quoteless strings are disabled")
+
+// ErrSingleQuotedStringDisabled is returned when a single quoted string is met while disabled.
+const ErrSingleQuotedStringDisabled = Error("single quoted strings are disabled")
+
+// ErrMissingComma is returned when a comma is missing while optional commas are disabled.
+const ErrMissingComma = Error("missing comma")
+
+// ErrDuplicateKey is wrapped by a DuplicateKeyError.
+const ErrDuplicateKey = Error("duplicate key")
+
+// ErrValueAfterRoot is returned when the root value is followed by another token.
+const ErrValueAfterRoot = Error("unexpected token after the root value")
+
+// ErrInexactDecimal is returned when the decimal result of an expression has too many fractional digits.
+const ErrInexactDecimal = Error("decimal result exceeds the decimal places")
+
+// ErrNegativeShiftCount is returned when the right operand of a shift operation is negative.
+const ErrNegativeShiftCount = Error("negative shift count")
+
+// ErrUnknownFunction is returned when a number expression calls an unknown function.
+const ErrUnknownFunction = Error("unknown function")
+
+// ErrUnknownConstant is returned when a number expression uses an unknown constant.
+const ErrUnknownConstant = Error("unknown constant")
+
+// ErrInvalidArgumentCount is returned when a function is called with too few or too many arguments.
+const ErrInvalidArgumentCount = Error("invalid number of function arguments")
+
+// ErrNotANumber is returned when the result of a power or a function is not a number.
+const ErrNotANumber = Error("result is not a number")
+
+// ErrFractionalSize is returned when a data size is not an integer number of bytes.
+const ErrFractionalSize = Error("size is not an integer number of bytes")
@@ -1,6 +1,7 @@
 package qjson
 
 import (
+	"errors"
 	"io"
 	"testing"
 )
@@ -23,3 +24,20 @@ func TestErrors(t *testing.T) {
 		t.Fatalf("expected %q, got %q", exp, out)
 	}
 }
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Decode([]byte("a: 1\nb: {\n  c: 'x\n}"))
+	var se *SyntaxError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if exp := (SyntaxError{Code: ErrNewlineInSingleQuoteString, Pos: Pos{Offset: 15, Line: 3, Column: 6}}); *se != exp {
+		t.Fatalf("expected %+v, got %+v", exp, *se)
+	}
+	if !errors.Is(err, ErrNewlineInSingleQuoteString) {
+		t.Fatalf("expected error to wrap %v", ErrNewlineInSingleQuoteString)
+	}
+	if exp := "newline in single quoted string at line 3 col 6"; err.Error() != exp {
+		t.Fatalf("expected %q, got %q", exp, err.Error())
+	}
+}
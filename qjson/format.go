@@ -0,0 +1,225 @@
+package qjson
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Format returns the canonical formatting of the QJSON text src. Members
+// and array values are written one per line, without commas, indented
+// with two spaces per nesting level. Member names are followed by a colon
+// and a space. Arrays of scalar values without comments are written on
+// one line. Comments are written on their own line in front of the node
+// they precede, or at the end of the line of the node they follow, with
+// the trailing comments of consecutive lines aligned. A single empty line
+// is kept where the text has one or more empty lines between members or
+// comments. Names, values, number expressions and comments are kept as
+// written. A root array is written as an array value, followed by the
+// comments of the document after it.
+func Format(src []byte) ([]byte, error) {
+	doc, err := Parse(src, WithComments())
+	if err != nil {
+		return nil, err
+	}
+	var f formatter
+	if o, ok := doc.Root.(*Object); ok {
+		f.members(o, 0)
+		f.comments(o.Trailing, 0)
+	} else {
+		c := doc.Root.(commented).comments()
+		f.comments(c.Leading, 0)
+		f.value(doc.Root, 0, "")
+		f.trailing(c.Trailing, doc.Root.EndPosition())
+	}
+	f.comments(doc.Trailing, 0)
+	return f.bytes(), nil
+}
+
+// formatter writes the canonical formatting of a document tree.
+type formatter struct {
+	lines   []formatLine
+	prevEnd int // last line of the previous element of the block, 0 at its start
+}
+
+// A formatLine is a line of the formatted text.
+type formatLine struct {
+	text    string // the line with its indentation
+	comment string // the trailing comments of the line
+}
+
+// line appends the line text with the indentation of level lvl.
+func (f *formatter) line(lvl int, text string) {
+	f.lines = append(f.lines, formatLine{text: strings.Repeat("  ", lvl) + text})
+}
+
+// gap appends an empty line when the element starting at line start is
+// separated from the previous element by empty lines.
+func (f *formatter) gap(start int) {
+	if f.prevEnd != 0 && start > f.prevEnd+1 {
+		f.lines = append(f.lines, formatLine{})
+	}
+}
+
+// comments writes the comments cs on their own lines at level lvl.
+func (f *formatter) comments(cs []*Comment, lvl int) {
+	for _, c := range cs {
+		f.gap(c.Pos.Line)
+		f.line(lvl, c.Text)
+		f.prevEnd = c.Pos.Line + strings.Count(c.Text, "\n")
+	}
+}
+
+// trailing writes the comments cs at the end of the last line, and
+// records end, the position following the node, as the end of the
+// previous element.
+func (f *formatter) trailing(cs []*Comment, end Pos) {
+	f.prevEnd = end.Line
+	if len(cs) == 0 {
+		return
+	}
+	texts := make([]string, len(cs))
+	for i, c := range cs {
+		texts[i] = c.Text
+		if l := c.Pos.Line + strings.Count(c.Text, "\n"); l > f.prevEnd {
+			f.prevEnd = l
+		}
+	}
+	f.lines[len(f.lines)-1].comment = strings.Join(texts, " ")
+}
+
+// members writes the members of o at level lvl followed by its inner
+// comments.
+func (f *formatter) members(o *Object, lvl int) {
+	f.prevEnd = 0
+	for _, m := range o.Members {
+		f.comments(m.Leading, lvl)
+		f.gap(m.Pos.Line)
+		lc := m.Value.(commented).comments().Leading
+		if _, ok := m.Value.(*Multiline); ok || len(lc) != 0 {
+			// the value starts its own line
+			f.line(lvl, m.Name.Raw+":")
+			f.prevEnd = m.Pos.Line
+			f.comments(lc, lvl+1)
+			f.value(m.Value, lvl+1, "")
+		} else {
+			f.value(m.Value, lvl, m.Name.Raw+": ")
+		}
+		f.trailing(m.Trailing, m.End)
+	}
+	f.comments(o.Inner, lvl)
+}
+
+// values writes the values of a at level lvl followed by its inner
+// comments.
+func (f *formatter) values(a *Array, lvl int) {
+	f.prevEnd = 0
+	for _, v := range a.Values {
+		c := v.(commented).comments()
+		f.comments(c.Leading, lvl)
+		f.gap(v.Position().Line)
+		f.value(v, lvl, "")
+		f.trailing(c.Trailing, v.EndPosition())
+	}
+	f.comments(a.Inner, lvl)
+}
+
+// value writes n at level lvl on a new line starting with prefix.
+func (f *formatter) value(n Node, lvl int, prefix string) {
+	switch x := n.(type) {
+	case *Object:
+		if len(x.Members) == 0 && len(x.Inner) == 0 {
+			f.line(lvl, prefix+"{}")
+			return
+		}
+		f.line(lvl, prefix+"{")
+		f.members(x, lvl+1)
+		f.line(lvl, "}")
+	case *Array:
+		if s, ok := oneLineArray(x); ok {
+			f.line(lvl, prefix+s)
+			return
+		}
+		f.line(lvl, prefix+"[")
+		f.values(x, lvl+1)
+		f.line(lvl, "]")
+	case *Multiline:
+		margin := strings.Repeat("  ", lvl)
+		f.line(lvl, strings.ReplaceAll(x.Raw, "\n"+x.Margin, "\n"+margin))
+	case *String:
+		f.line(lvl, prefix+x.Raw)
+	case *Number:
+		f.line(lvl, prefix+x.Raw)
+	case *Literal:
+		f.line(lvl, prefix+x.Raw)
+	}
+}
+
+// oneLineArray returns the one line text of a when it is an array of
+// scalar values without comments.
+func oneLineArray(a *Array) (string, bool) {
+	if len(a.Inner) != 0 {
+		return "", false
+	}
+	raws := make([]string, len(a.Values))
+	for i, v := range a.Values {
+		if c := v.(commented).comments(); len(c.Leading) != 0 || len(c.Trailing) != 0 {
+			return "", false
+		}
+		switch x := v.(type) {
+		case *Object:
+			if len(x.Members) != 0 || len(x.Inner) != 0 {
+				return "", false
+			}
+			raws[i] = "{}"
+		case *Array:
+			if len(x.Values) != 0 || len(x.Inner) != 0 {
+				return "", false
+			}
+			raws[i] = "[]"
+		case *String:
+			raws[i] = x.Raw
+		case *Number:
+			raws[i] = x.Raw
+		case *Literal:
+			raws[i] = x.Raw
+		default:
+			return "", false
+		}
+	}
+	return "[" + strings.Join(raws, ", ") + "]", true
+}
+
+// bytes returns the formatted text. The trailing comments of consecutive
+// lines are aligned.
+func (f *formatter) bytes() []byte {
+	var b strings.Builder
+	for i := 0; i < len(f.lines); {
+		// j is the end of the run of aligned lines starting at i
+		j, width := i, 0
+		for j < len(f.lines) && alignable(f.lines[j]) {
+			if w := utf8.RuneCountInString(f.lines[j].text); w > width {
+				width = w
+			}
+			j++
+		}
+		if j == i {
+			j, width = i+1, utf8.RuneCountInString(f.lines[i].text)
+		}
+		for _, l := range f.lines[i:j] {
+			b.WriteString(l.text)
+			if l.comment != "" {
+				b.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(l.text)+1))
+				b.WriteString(l.comment)
+			}
+			b.WriteByte('\n')
+		}
+		i = j
+	}
+	return []byte(b.String())
+}
+
+// alignable returns true if the trailing comment of l may be aligned with
+// the trailing comments of the neighbor lines.
+func alignable(l formatLine) bool {
+	return l.comment != "" && !strings.Contains(l.text, "\n") && !strings.Contains(l.comment, "\n")
+}
@@ -0,0 +1,49 @@
+package qjson
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		err string
+	}{
+		// 0
+		{in: "", out: ""},
+		{in: "a :  b\n  c:1,d:true", out: "a: b\nc: 1\nd: true\n"},
+		{in: "a: 2 *  3\nb: 0x1F, c: yes, d: \"x\"\ne: 'y'", out: "a: 2 *  3\nb: 0x1F\nc: yes\nd: \"x\"\ne: 'y'\n"},
+		{in: "a: [\n 1,\n  2\n]\nb: [{}, [], x]\nc: [[1], {d: 2}]", out: "a: [1, 2]\nb: [{}, [], x]\nc: [\n  [1]\n  {\n    d: 2\n  }\n]\n"},
+		{in: "a: {b: {c: {}}}", out: "a: {\n  b: {\n    c: {}\n  }\n}\n"},
+		// 5
+		{in: "a:\n      `\\r\\n\n      x\n        y\n      `\nb: [\n`\\n\n`]", out: "a:\n  `\\r\\n\n  x\n    y\n  `\nb: [\n  `\\n\n  `\n]\n"},
+		{in: "# head\n\n\n\na: 1 # c\nbb: 2 // d\n\nccc: 3 /* e */", out: "# head\n\na: 1  # c\nbb: 2 // d\n\nccc: 3 /* e */\n"},
+		{in: "a: [1, # one\n 2\n # end\n]\nb: {\n  # inner\n}", out: "a: [\n  1 # one\n  2\n  # end\n]\nb: {\n  # inner\n}\n"},
+		{in: "a: # value\n 1\nb: {x: 1} # c\n/* multi\nline */ c: 2", out: "a:\n  # value\n  1\nb: {\n  x: 1\n} # c\n/* multi\nline */\nc: 2\n"},
+		{in: "a: {", err: "unclosed object at line 1 col 4"},
+		// 10
+		{in: "# hosts\n[alpha,\n beta]", out: "# hosts\n[alpha, beta]\n"},
+		{in: "[{a: 1}]", out: "[\n  {\n    a: 1\n  }\n]\n"},
+		{in: "[1, 2] # c", out: "[1, 2] # c\n"},
+		{in: "# head\n[\n  1 # one\n]\n# tail", out: "# head\n[\n  1 # one\n]\n# tail\n"},
+		{in: "[1] # c\n\n\n# tail", out: "[1] # c\n\n# tail\n"},
+	}
+	for i, test := range tests {
+		out, err := Format([]byte(test.in))
+		if e2s(err) != test.err {
+			t.Fatalf("%d expected error %q, got %q", i, test.err, e2s(err))
+		}
+		if err != nil {
+			continue
+		}
+		if string(out) != test.out {
+			t.Fatalf("%d expected\n%q\ngot\n%q", i, test.out, out)
+		}
+		if again, err := Format(out); err != nil || string(again) != string(out) {
+			t.Fatalf("%d expected same formatting, got %q %v", i, again, err)
+		}
+		exp, _ := Decode([]byte(test.in))
+		if res, err := Decode(out); err != nil || string(res) != string(exp) {
+			t.Fatalf("%d expected %q, got %q %v", i, exp, res, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package qjson
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatError returns the message of err followed by the lines of the
+// QJSON text src where the error is located, with a ^ under the column
+// of the error. The end of input is also shown for errors like an
+// unclosed object, where the error is located at the opening brace, and
+// the first key is shown for a duplicate key error. Each error of an
+// ErrorList is formatted this way. Errors without a position are returned
+// as their message.
+func FormatError(err error, src []byte) string {
+	var list ErrorList
+	if errors.As(err, &list) {
+		msgs := make([]string, len(list))
+		for i, e := range list {
+			msgs[i] = FormatError(e, src)
+		}
+		return strings.Join(msgs, "\n")
+	}
+	var locs []errorLocation
+	var se *SyntaxError
+	var ute *UnmarshalTypeError
+	var dke *DuplicateKeyError
+	switch {
+	case errors.As(err, &se):
+		locs = append(locs, errorLocation{pos: se.Pos})
+		switch se.Code {
+		case ErrUnclosedObject, ErrUnclosedArray, ErrUnclosedDoubleQuoteString, ErrUnclosedSingleQuoteString,
+			ErrUnclosedSlashStarComment, ErrUnclosedMultiline:
+			if end := endPosition(src); end.Line != se.Line || end.Column != se.Column {
+				locs = append(locs, errorLocation{pos: end, label: "end of input"})
+			}
+		}
+	case errors.As(err, &ute):
+		locs = append(locs, errorLocation{pos: ute.Pos})
+	case errors.As(err, &dke):
+		locs = append(locs, errorLocation{pos: dke.First, label: "first key"}, errorLocation{pos: dke.Pos})
+	default:
+		return err.Error()
+	}
+	width := len(strconv.Itoa(locs[len(locs)-1].pos.Line))
+	margin := strings.Repeat(" ", width)
+	var b strings.Builder
+	b.WriteString(err.Error())
+	for i := 0; i < len(locs); {
+		// j is the end of the locations on the line of locs[i]
+		j := i + 1
+		for j < len(locs) && locs[j].pos.Line == locs[i].pos.Line {
+			j++
+		}
+		line := sourceLine(src, locs[i].pos)
+		b.WriteString("\n" + strings.Repeat(" ", width-len(strconv.Itoa(locs[i].pos.Line))))
+		b.WriteString(strconv.Itoa(locs[i].pos.Line) + " | " + line)
+		b.WriteString("\n" + margin + " | ")
+		runes := []rune(line)
+		var col int
+		for _, l := range locs[i:j] {
+			for ; col < l.pos.Column-1; col++ {
+				// keep the tabs of the line to align the ^ with the column
+				if col < len(runes) && runes[col] == '\t' {
+					b.WriteByte('\t')
+				} else {
+					b.WriteByte(' ')
+				}
+			}
+			b.WriteByte('^')
+			col++
+			if l.label != "" {
+				b.WriteString(" " + l.label)
+				col += utf8.RuneCountInString(l.label) + 1
+			}
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// An errorLocation is a location shown by FormatError.
+type errorLocation struct {
+	pos   Pos
+	label string
+}
+
+// sourceLine returns the line of src at position p without its newline.
+func sourceLine(src []byte, p Pos) string {
+	if p.Offset > len(src) {
+		return ""
+	}
+	b := bytes.LastIndexByte(src[:p.Offset], '\n') + 1
+	e := bytes.IndexByte(src[b:], '\n')
+	if e < 0 {
+		e = len(src)
+	} else {
+		e += b
+	}
+	return strings.TrimSuffix(string(src[b:e]), "\r")
+}
+
+// endPosition returns the position of the end of src.
+func endPosition(src []byte) Pos {
+	b := bytes.LastIndexByte(src, '\n') + 1
+	return Pos{
+		Offset: len(src),
+		Line:   bytes.Count(src, []byte("\n")) + 1,
+		Column: utf8.RuneCount(src[b:]) + 1,
+	}
+}
@@ -0,0 +1,48 @@
+package qjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatError(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		// 0
+		{in: "a: 1\nb:\t'x\nc: 2", out: "newline in single quoted string at line 2 col 4\n2 | b:\t'x\n  |   \t^"},
+		{in: "a: 1\nb: {\n  c: 2\n", out: "unclosed object at line 2 col 4\n2 | b: {\n  |    ^\n4 | \n  | ^ end of input"},
+		{in: "a: [1, 2", out: "unclosed array at line 1 col 5\n1 | a: [1, 2\n  |     ^   ^ end of input"},
+		{in: "a: 1\r\nb: /*\r\n\r\n\r\n\r\n\r\n\r\n\r\n\r\nx", out: "unclosed /*...*/ comment at line 2 col 4\n 2 | b: /*\n   |    ^\n10 | x\n   |  ^ end of input"},
+		{in: "é: 1/0", out: "division by zero at line 1 col 5\n1 | é: 1/0\n  |     ^"},
+	}
+	for i, test := range tests {
+		_, err := Decode([]byte(test.in))
+		if out := FormatError(err, []byte(test.in)); out != test.out {
+			t.Fatalf("%d expected\n%s\ngot\n%s", i, test.out, out)
+		}
+	}
+	in := []byte("a: 'x\nb: [1,]")
+	_, err := DecodeAll(in)
+	exp := "newline in single quoted string at line 1 col 4\n1 | a: 'x\n  |    ^\nexpect value after comma at line 2 col 7\n2 | b: [1,]\n  |       ^"
+	if out := FormatError(err, in); out != exp {
+		t.Fatalf("expected\n%s\ngot\n%s", exp, out)
+	}
+	var v struct{ A int }
+	in = []byte("a: x")
+	err = Unmarshal(in, &v)
+	exp = "cannot unmarshal string into Go struct field a of type int at line 1 col 4\n1 | a: x\n  |    ^"
+	if out := FormatError(err, in); out != exp {
+		t.Fatalf("expected\n%s\ngot\n%s", exp, out)
+	}
+	in = []byte("a: 1\n'a': 2")
+	_, err = DecodeWithOptions(in, DecodeOptions{Duplicates: DuplicateError})
+	exp = "duplicate key \"a\" at line 2 col 1, first at line 1 col 1\n1 | a: 1\n  | ^ first key\n2 | 'a': 2\n  | ^"
+	if out := FormatError(err, in); out != exp {
+		t.Fatalf("expected\n%s\ngot\n%s", exp, out)
+	}
+	if out := FormatError(errors.New("x"), nil); out != "x" {
+		t.Fatalf("expected x, got %s", out)
+	}
+}
@@ -0,0 +1,119 @@
+package qjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// FromJSONOptions are the options of FromJSON. The zero value writes
+// quoteless strings when possible, no commas and an indent of two spaces.
+type FromJSONOptions struct {
+	Indent string    // indentation of nested values, two spaces when empty
+	Commas bool      // separate members and array values with commas
+	Quote  QuoteKind // quoting of names and strings, Quoteless when safe
+}
+
+// FromJSON converts the JSON text jsonText into a QJSON text. The top level
+// value must be an object. The order of members and the number texts are
+// kept. Names and strings are quoteless when it is safe and Quote is
+// Quoteless, otherwise they are quoted with the Quote kind, double quotes
+// by default. Strings containing newlines are written as multiline strings
+// with the \n or \r\n newline specifier matching their newlines when Quote
+// is Quoteless. A nil opts is equivalent to a zero FromJSONOptions.
+func FromJSON(jsonText []byte, opts *FromJSONOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &FromJSONOptions{}
+	}
+	d := json.NewDecoder(bytes.NewReader(jsonText))
+	d.UseNumber()
+	v, err := readJSON(d, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("invalid character after top-level value")
+		}
+		return nil, err
+	}
+	o, ok := v.(jsonObject)
+	if !ok {
+		return nil, fmt.Errorf("%w: top level JSON value", ErrNotAnObject)
+	}
+	e := &encoder{indent: opts.Indent, commas: opts.Commas, quote: opts.Quote}
+	if e.indent == "" {
+		e.indent = "  "
+	}
+	e.jsonMembers(reflect.ValueOf(o), 0)
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.buf.Len() != 0 {
+		e.buf.WriteByte('\n')
+	}
+	return e.buf.Bytes(), nil
+}
+
+// jsonObject is a JSON object with its members in order.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	name  string
+	value interface{}
+}
+
+// readJSON reads a JSON value from d at nesting level lvl. Objects are
+// returned as jsonObject, arrays as []interface{} and numbers as
+// json.Number.
+func readJSON(d *json.Decoder, lvl int) (interface{}, error) {
+	t, err := jsonToken(d)
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := t.(json.Delim)
+	if !ok {
+		return t, nil
+	}
+	if lvl == DefaultMaxDepth {
+		return nil, ErrMaxObjectArrayDepth
+	}
+	if delim == '{' {
+		o := jsonObject{}
+		for d.More() {
+			name, err := jsonToken(d)
+			if err != nil {
+				return nil, err
+			}
+			v, err := readJSON(d, lvl+1)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, jsonMember{name: name.(string), value: v})
+		}
+		_, err = jsonToken(d)
+		return o, err
+	}
+	a := []interface{}{}
+	for d.More() {
+		v, err := readJSON(d, lvl+1)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	_, err = jsonToken(d)
+	return a, err
+}
+
+// jsonToken returns the next token of d. The end of input is unexpected.
+func jsonToken(d *json.Decoder) (json.Token, error) {
+	t, err := d.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return t, err
+}
@@ -0,0 +1,56 @@
+package qjson
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFromJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		opts *FromJSONOptions
+		out  string
+		err  string
+	}{
+		// 0
+		{in: `{}`, out: ""},
+		{in: `{"b": 1, "a": "x y", "c": true}`, out: "b: 1\na: x y\nc: true\n"},
+		{in: `{"n": 1.50e+10, "s": "1.5", "t": "true", "q": "a, b", "k y": null}`, out: "n: 1.50e+10\ns: \"1.5\"\nt: \"true\"\nq: \"a, b\"\nk y: null\n"},
+		{in: `{"a": [1, 2, "x"], "b": [{"c": []}, {}], "d": {"e": {}}}`, out: "a: [1, 2, x]\nb: [\n  {\n    c: []\n  }\n  {}\n]\nd: {\n  e: {}\n}\n"},
+		{in: `{"m": "a\nb\n", "r": "a\r\nb", "x": "a\rb\nc"}`, out: "m:\n  `\\n\n  a\n  b\n  `\nr:\n  `\\r\\n\n  a\n  b`\nx: \"a\\rb\\nc\"\n"},
+		// 5
+		{in: `{"a": 1, "b": {"c": 2, "d": [{"x": 1, "y": 2}, 3]}}`, opts: &FromJSONOptions{Indent: "\t", Commas: true}, out: "a: 1,\nb: {\n\tc: 2,\n\td: [\n\t\t{\n\t\t\tx: 1,\n\t\t\ty: 2\n\t\t},\n\t\t3\n\t]\n}\n"},
+		{in: `{"a": "it's \"x\"", "b": "y\n"}`, opts: &FromJSONOptions{Quote: SingleQuoted}, out: "'a': 'it\\'s \"x\"'\n'b': 'y\\n'\n"},
+		{in: `{"a": "b"}`, opts: &FromJSONOptions{Quote: DoubleQuoted}, out: "\"a\": \"b\"\n"},
+		{in: `[1]`, err: "not an object: top level JSON value"},
+		{in: `{"a": 1} x`, err: "invalid character 'x' looking for beginning of value"},
+		// 10
+		{in: `{"a": [1`, err: "unexpected end of JSON input"},
+		{in: `{"a": 1}{}`, err: "invalid character after top-level value"},
+	}
+	for i, test := range tests {
+		out, err := FromJSON([]byte(test.in), test.opts)
+		if e2s(err) != test.err {
+			t.Fatalf("%d expected error %q, got %q", i, test.err, e2s(err))
+		}
+		if err != nil {
+			continue
+		}
+		if string(out) != test.out {
+			t.Fatalf("%d expected %q, got %q", i, test.out, out)
+		}
+		jsonOut, err := Decode(out)
+		if err != nil {
+			t.Fatalf("%d unexpected error %v decoding %q", i, err, out)
+		}
+		var v1, v2 interface{}
+		if err := json.Unmarshal(jsonOut, &v1); err != nil {
+			t.Fatalf("%d unexpected error %v", i, err)
+		}
+		json.Unmarshal([]byte(test.in), &v2)
+		if !reflect.DeepEqual(v1, v2) {
+			t.Fatalf("%d expected %v, got %v", i, v2, v1)
+		}
+	}
+}
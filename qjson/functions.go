@@ -0,0 +1,382 @@
+package qjson
+
+import (
+	"math"
+	"math/big"
+)
+
+// A builtin is a function of the number expressions. Its arguments and its
+// result are int, uint64, float64 or *big.Rat values.
+type builtin struct {
+	minArgs int // minimum number of arguments
+	maxArgs int // maximum number of arguments, or -1 when unlimited
+	f       func(args []interface{}) (interface{}, error)
+}
+
+var builtins = map[string]builtin{}
+
+// to get rid of initialization cycle error
+func init() {
+	builtins = map[string]builtin{
+		"min":   {minArgs: 1, maxArgs: -1, f: builtinMin},
+		"max":   {minArgs: 1, maxArgs: -1, f: builtinMax},
+		"abs":   {minArgs: 1, maxArgs: 1, f: builtinAbs},
+		"round": {minArgs: 1, maxArgs: 1, f: builtinRound},
+		"floor": {minArgs: 1, maxArgs: 1, f: builtinFloor},
+		"ceil":  {minArgs: 1, maxArgs: 1, f: builtinCeil},
+		"sqrt":  {minArgs: 1, maxArgs: 1, f: builtinSqrt},
+	}
+}
+
+// identifierLen returns the length of the identifier at the start of p.
+// An identifier starts with a letter or an underscore, followed by
+// letters, digits or underscores.
+func identifierLen(p []byte) int {
+	var n int
+	for n < len(p) && (p[n] == '_' || inRange(p[n]|0x20, 'a', 'z') || (n > 0 && isIntDigit(p[n]))) {
+		n++
+	}
+	return n
+}
+
+// isFunctionCall returns true if p starts with the name of a built-in
+// function followed by an open parenthesis.
+func isFunctionCall(p []byte) bool {
+	n := identifierLen(p)
+	if n == 0 || n == len(p) || p[n] != '(' {
+		return false
+	}
+	_, ok := builtins[string(p[:n])]
+	return ok
+}
+
+// isQuotelessText returns true if the text p starting with a call of a
+// built-in function, like min(x), is not a valid number expression because
+// of the error err. It is then a quoteless string.
+func isQuotelessText(p []byte, err error) bool {
+	if !isFunctionCall(p[exprStart(p):]) {
+		return false
+	}
+	switch err {
+	case ErrInvalidNumericExpression, ErrUnknownConstant, ErrUnknownFunction, ErrInvalidArgumentCount,
+		ErrUnclosedParenthesis, ErrUnopenedParenthesis:
+		return true
+	}
+	return false
+}
+
+func nudIdentifier(tk *numTokenizer, t numToken) interface{} {
+	name := t.val.(string)
+	if tk.token().tag != tagOpenParen {
+		v, ok := tk.constants[name]
+		if !ok {
+			tk.setErrorAndPos(ErrUnknownConstant, t.pos)
+			return nil
+		}
+		return floatValue(v)
+	}
+	if f, ok := tk.functions[name]; ok {
+		return tk.callFunction(t, f)
+	}
+	f, ok := builtins[name]
+	if !ok {
+		tk.setErrorAndPos(ErrUnknownFunction, t.pos)
+		return nil
+	}
+	args := tk.arguments()
+	if args == nil {
+		return nil
+	}
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		tk.setErrorAndPos(ErrInvalidArgumentCount, t.pos)
+		return nil
+	}
+	res, err := f.f(args)
+	if err != nil {
+		tk.setErrorAndPos(err, t.pos)
+		return nil
+	}
+	return res
+}
+
+// callFunction calls the function f of the options named by t with the
+// arguments starting at the current open parenthesis.
+func (tk *numTokenizer) callFunction(t numToken, f func(args ...float64) (float64, error)) interface{} {
+	args := tk.arguments()
+	if args == nil {
+		return nil
+	}
+	fargs := make([]float64, len(args))
+	for i, v := range args {
+		fargs[i] = toFloat64(v)
+	}
+	res, err := f(fargs...)
+	if err == nil && math.IsNaN(res) {
+		err = ErrNotANumber
+	}
+	if err != nil {
+		tk.setErrorAndPos(err, t.pos)
+		return nil
+	}
+	return floatValue(res)
+}
+
+// arguments evaluates the comma separated arguments of a function call
+// starting at the current open parenthesis. On return, the current token
+// is the token following the close parenthesis. It returns nil when an
+// error occured.
+func (tk *numTokenizer) arguments() []interface{} {
+	open := tk.token()
+	tk.nextToken()
+	args := []interface{}{}
+	if tk.token().tag == tagCloseParen {
+		tk.nextToken()
+		return args
+	}
+	for {
+		arg := tk.expression(precedenceTable[tagOpenParen])
+		if arg == nil {
+			if tk.tk.val.(error) == ErrEndOfInput {
+				tk.setErrorAndPos(ErrInvalidNumericExpression, open.pos)
+			}
+			return nil
+		}
+		args = append(args, arg)
+		switch tk.token().tag {
+		case tagComma:
+			tk.nextToken()
+		case tagCloseParen:
+			tk.nextToken()
+			return args
+		default:
+			tk.setErrorAndPos(ErrUnclosedParenthesis, open.pos)
+			return nil
+		}
+	}
+}
+
+// compare returns -1, 0 or +1 when x is less than, equal to or greater
+// than y.
+func compare(x, y interface{}) int {
+	// a negative int is less than any uint64
+	if a, ok := x.(int); ok && a < 0 {
+		if _, ok := y.(uint64); ok {
+			return -1
+		}
+	}
+	if b, ok := y.(int); ok && b < 0 {
+		if _, ok := x.(uint64); ok {
+			return 1
+		}
+	}
+	x, y = normalizeTypes(x, y)
+	switch a := x.(type) {
+	case int:
+		return compareInts(a < y.(int), a > y.(int))
+	case uint64:
+		return compareInts(a < y.(uint64), a > y.(uint64))
+	case *big.Rat:
+		return a.Cmp(y.(*big.Rat))
+	}
+	return compareInts(x.(float64) < y.(float64), x.(float64) > y.(float64))
+}
+
+func compareInts(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func builtinMin(args []interface{}) (interface{}, error) {
+	res := args[0]
+	for _, v := range args[1:] {
+		if compare(v, res) < 0 {
+			res = v
+		}
+	}
+	return res, nil
+}
+
+func builtinMax(args []interface{}) (interface{}, error) {
+	res := args[0]
+	for _, v := range args[1:] {
+		if compare(v, res) > 0 {
+			res = v
+		}
+	}
+	return res, nil
+}
+
+func builtinAbs(args []interface{}) (interface{}, error) {
+	switch x := args[0].(type) {
+	case int:
+		if x >= 0 {
+			return x, nil
+		}
+		if x == math.MinInt64 {
+			return uint64(1 << 63), nil
+		}
+		return -x, nil
+	case uint64:
+		return x, nil
+	case *big.Rat:
+		return new(big.Rat).Abs(x), nil
+	}
+	return math.Abs(args[0].(float64)), nil
+}
+
+func builtinRound(args []interface{}) (interface{}, error) {
+	return roundValue(args[0]), nil
+}
+
+// roundValue returns v rounded to the nearest integer, with halves rounded
+// away from zero.
+func roundValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int, uint64:
+		return x
+	case *big.Rat:
+		half := big.NewRat(1, 2)
+		if x.Sign() < 0 {
+			half.Neg(half)
+		}
+		r := new(big.Rat).Add(x, half)
+		return bigIntValue(new(big.Int).Quo(r.Num(), r.Denom()))
+	}
+	return floatIntValue(math.Round(v.(float64)))
+}
+
+func builtinFloor(args []interface{}) (interface{}, error) {
+	switch x := args[0].(type) {
+	case int, uint64:
+		return x, nil
+	case *big.Rat:
+		// the euclidean division by the positive denominator is the floor
+		return bigIntValue(new(big.Int).Div(x.Num(), x.Denom())), nil
+	}
+	return floatIntValue(math.Floor(args[0].(float64))), nil
+}
+
+func builtinCeil(args []interface{}) (interface{}, error) {
+	switch x := args[0].(type) {
+	case int, uint64:
+		return x, nil
+	case *big.Rat:
+		z := new(big.Int).Div(new(big.Int).Neg(x.Num()), x.Denom())
+		return bigIntValue(z.Neg(z)), nil
+	}
+	return floatIntValue(math.Ceil(args[0].(float64))), nil
+}
+
+func builtinSqrt(args []interface{}) (interface{}, error) {
+	res := math.Sqrt(toFloat64(args[0]))
+	if math.IsNaN(res) {
+		return nil, ErrNotANumber
+	}
+	return res, nil
+}
+
+// bigIntValue returns the integer z of an exact value as an int or an
+// uint64 when it is in their range, otherwise as an exact *big.Rat.
+func bigIntValue(z *big.Int) interface{} {
+	if z.IsInt64() {
+		return int(z.Int64())
+	}
+	if z.IsUint64() {
+		return z.Uint64()
+	}
+	return new(big.Rat).SetInt(z)
+}
+
+// floatValue returns f as an int or an uint64 when it is an integer in
+// their range, otherwise as a float64.
+func floatValue(f float64) interface{} {
+	if f != math.Trunc(f) {
+		return f
+	}
+	return floatIntValue(f)
+}
+
+// floatIntValue returns the integer float64 value f as an int or an uint64
+// when it is in their range, otherwise as a float64.
+func floatIntValue(f float64) interface{} {
+	switch {
+	case f >= -(1<<63) && f < 1<<63:
+		return int(f)
+	case f >= 0 && f < 1<<64:
+		return uint64(f)
+	}
+	return f
+}
+
+// power returns x raised to the power y by the operator t. The result is
+// exact when x is an integer or a *big.Rat and y is an integer. It returns
+// nil when an error occured.
+func (tk *numTokenizer) power(t numToken, x, y interface{}) interface{} {
+	if r, ok := y.(*big.Rat); ok && r.IsInt() && r.Num().IsInt64() {
+		y = int(r.Num().Int64())
+	}
+	var n uint64
+	var neg bool
+	switch e := y.(type) {
+	case int:
+		if e < 0 {
+			n, neg = uint64(-e), true
+		} else {
+			n = uint64(e)
+		}
+	case uint64:
+		n = e
+	default:
+		return tk.floatPower(t, x, y)
+	}
+	switch b := x.(type) {
+	case int, uint64:
+		if !neg {
+			return tk.intPower(t, bigInt(b), n)
+		}
+	case *big.Rat:
+		// large exponents are computed with floats
+		if n > 1024 {
+			return tk.floatPower(t, x, y)
+		}
+		e := new(big.Int).SetUint64(n)
+		z := new(big.Rat).SetFrac(new(big.Int).Exp(b.Num(), e, nil), new(big.Int).Exp(b.Denom(), e, nil))
+		if neg {
+			if z.Sign() == 0 {
+				tk.setErrorAndPos(ErrDivisionByZero, t.pos)
+				return nil
+			}
+			z.Inv(z)
+		}
+		return z
+	}
+	return tk.floatPower(t, x, y)
+}
+
+// intPower returns x raised to the power n by the operator t as an int or
+// an uint64 when it is in their range. Otherwise the overflow is handled
+// by intOverflow.
+func (tk *numTokenizer) intPower(t numToken, x *big.Int, n uint64) interface{} {
+	// |x| > 1 raised to a power greater than 64 is out of the uint64 range
+	if n > 64 && x.CmpAbs(big.NewInt(1)) > 0 {
+		f, _ := new(big.Float).SetInt(x).Float64()
+		return tk.intOverflow(t, math.Pow(f, float64(n)))
+	}
+	return tk.intResult(t, x.Exp(x, new(big.Int).SetUint64(n), nil))
+}
+
+// floatPower returns x raised to the power y by the operator t as a
+// float64. It returns nil when the result is not a number.
+func (tk *numTokenizer) floatPower(t numToken, x, y interface{}) interface{} {
+	res := math.Pow(toFloat64(x), toFloat64(y))
+	if math.IsNaN(res) {
+		tk.setErrorAndPos(ErrNotANumber, t.pos)
+		return nil
+	}
+	return res
+}
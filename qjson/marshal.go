@@ -0,0 +1,587 @@
+package qjson
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Marshal returns the QJSON text of v. It is MarshalIndent with an empty
+// prefix and an indent of two spaces.
+func Marshal(v interface{}) ([]byte, error) {
+	return MarshalIndent(v, "", "  ")
+}
+
+// MarshalIndent returns the QJSON text of v. Each line starts with prefix
+// followed by one or more copies of indent according to the nesting.
+//
+// The value v must be a struct or a map, possibly behind pointers or
+// interfaces. It is encoded as the top level object. The values follow
+// the rules of encoding/json, with the qjson tag taking precedence over
+// the json tag. The members are written one per line, without commas.
+// Strings are quoteless when they can be read back as the same string,
+// and strings containing newlines are written as multiline strings.
+// Arrays of scalar values are written on one line.
+func MarshalIndent(v interface{}, prefix, indent string) ([]byte, error) {
+	e := &encoder{prefix: prefix, indent: indent}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			break
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		e.structMembers(rv, 0)
+	case reflect.Map:
+		e.mapMembers(rv, 0)
+	default:
+		return nil, &UnsupportedTypeError{reflect.TypeOf(v)}
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.buf.Len() != 0 {
+		e.buf.WriteByte('\n')
+	}
+	return e.buf.Bytes(), nil
+}
+
+// An UnsupportedTypeError is returned by Marshal when attempting to encode
+// an unsupported value type, or a top level value that is not an object.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	if e.Type == nil {
+		return "qjson: unsupported type: nil"
+	}
+	return "qjson: unsupported type: " + e.Type.String()
+}
+
+// An UnsupportedValueError is returned by Marshal when attempting to
+// encode an unsupported value.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "qjson: unsupported value: " + e.Str
+}
+
+// A MarshalerError is returned by Marshal when the MarshalJSON or
+// MarshalText method of a value returns an error.
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+}
+
+func (e *MarshalerError) Error() string {
+	return "qjson: error calling marshal method for type " + e.Type.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the marshal method.
+func (e *MarshalerError) Unwrap() error { return e.Err }
+
+// encoder writes the QJSON text of values in buf.
+type encoder struct {
+	buf    bytes.Buffer
+	prefix string
+	indent string
+	commas bool      // separate members and array values with commas
+	quote  QuoteKind // quoting of strings, Quoteless when safe by default
+	err    error
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	numberType        = reflect.TypeOf(json.Number(""))
+	jsonObjectType    = reflect.TypeOf(jsonObject(nil))
+)
+
+// newline writes a newline followed by the indentation of level lvl.
+func (e *encoder) newline(lvl int) {
+	e.buf.WriteByte('\n')
+	e.buf.WriteString(e.margin(lvl))
+}
+
+// margin returns the indentation of level lvl.
+func (e *encoder) margin(lvl int) string {
+	return e.prefix + strings.Repeat(e.indent, lvl)
+}
+
+// member writes the member name with value v at level lvl. first is true
+// for the first member of the object.
+func (e *encoder) member(name string, v reflect.Value, lvl int, first bool) {
+	if !first && e.commas {
+		e.buf.WriteByte(',')
+	}
+	if e.buf.Len() != 0 || lvl != 0 {
+		e.newline(lvl)
+	} else {
+		e.buf.WriteString(e.margin(lvl))
+	}
+	if e.quote == Quoteless {
+		e.buf.WriteString(quoteName(name))
+	} else {
+		e.quoted(name)
+	}
+	e.buf.WriteByte(':')
+	if s, nl := e.multiline(v); nl != "" {
+		e.newline(lvl + 1)
+		e.multilineString(s, nl, lvl+1)
+		return
+	}
+	e.buf.WriteByte(' ')
+	e.value(v, lvl)
+}
+
+// structMembers writes the members of struct v at level lvl.
+func (e *encoder) structMembers(v reflect.Value, lvl int) int {
+	var n int
+	for _, f := range cachedFields(v.Type()) {
+		fv := v
+		for _, i := range f.index {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv = reflect.Value{}
+					break
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(i)
+		}
+		if !fv.IsValid() || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+		e.member(f.name, fv, lvl, n == 0)
+		n++
+	}
+	return n
+}
+
+// mapMembers writes the members of map v at level lvl, sorted by name.
+func (e *encoder) mapMembers(v reflect.Value, lvl int) int {
+	type kv struct {
+		k string
+		v reflect.Value
+	}
+	var kvs []kv
+	iter := v.MapRange()
+	for iter.Next() {
+		k, err := mapKey(iter.Key())
+		if err != nil {
+			e.setError(err)
+			return 0
+		}
+		kvs = append(kvs, kv{k, iter.Value()})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].k < kvs[j].k })
+	for i, kv := range kvs {
+		e.member(kv.k, kv.v, lvl, i == 0)
+	}
+	return len(kvs)
+}
+
+// jsonMembers writes the members of the JSON object v at level lvl.
+func (e *encoder) jsonMembers(v reflect.Value, lvl int) int {
+	o := v.Interface().(jsonObject)
+	for i, m := range o {
+		e.member(m.name, reflect.ValueOf(&o[i].value).Elem(), lvl, i == 0)
+	}
+	return len(o)
+}
+
+// mapKey returns the member name of map key k.
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		if err != nil {
+			return "", &MarshalerError{k.Type(), err}
+		}
+		return string(b), nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", &UnsupportedTypeError{k.Type()}
+}
+
+func (e *encoder) setError(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+// value writes v at level lvl. Requires that v is not a multiline string.
+func (e *encoder) value(v reflect.Value, lvl int) {
+	if e.err != nil {
+		return
+	}
+	if lvl >= DefaultMaxDepth {
+		e.setError(&UnsupportedValueError{v, ErrMaxObjectArrayDepth.Error()})
+		return
+	}
+	if !v.IsValid() {
+		e.buf.WriteString("null")
+		return
+	}
+	if isJSONMarshaler(v) {
+		if v.Kind() != reflect.Ptr && !v.Type().Implements(jsonMarshalerType) {
+			v = v.Addr()
+		}
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			e.buf.WriteString("null")
+			return
+		}
+		b, err := v.Interface().(json.Marshaler).MarshalJSON()
+		if err == nil {
+			var buf bytes.Buffer
+			if err = json.Compact(&buf, b); err == nil {
+				e.buf.Write(buf.Bytes())
+				return
+			}
+		}
+		e.setError(&MarshalerError{v.Type(), err})
+		return
+	}
+	if s, ok, err := textValue(v); ok {
+		if err != nil {
+			e.setError(err)
+			return
+		}
+		e.string(s)
+		return
+	}
+	switch v.Type() {
+	case numberType:
+		if n := v.String(); n != "" && n[0] != '+' && json.Valid([]byte(n)) {
+			e.buf.WriteString(n)
+		} else {
+			e.setError(&UnsupportedValueError{v, "invalid number " + quote(n)})
+		}
+		return
+	case jsonObjectType:
+		e.object(v, lvl, e.jsonMembers)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		e.buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		e.float(v)
+	case reflect.String:
+		e.string(v.String())
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return
+		}
+		e.value(v.Elem(), lvl)
+	case reflect.Struct:
+		e.object(v, lvl, e.structMembers)
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return
+		}
+		e.object(v, lvl, e.mapMembers)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteString("null")
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && !reflect.PtrTo(v.Type().Elem()).Implements(jsonMarshalerType) &&
+			!reflect.PtrTo(v.Type().Elem()).Implements(textMarshalerType) {
+			e.string(base64.StdEncoding.EncodeToString(v.Bytes()))
+			return
+		}
+		e.array(v, lvl)
+	case reflect.Array:
+		e.array(v, lvl)
+	default:
+		e.setError(&UnsupportedTypeError{v.Type()})
+	}
+}
+
+// isJSONMarshaler returns true if v or its address implements json.Marshaler.
+func isJSONMarshaler(v reflect.Value) bool {
+	return v.Type().Implements(jsonMarshalerType) || (v.CanAddr() && reflect.PtrTo(v.Type()).Implements(jsonMarshalerType))
+}
+
+// textValue returns the text of v when it implements encoding.TextMarshaler.
+func textValue(v reflect.Value) (string, bool, error) {
+	if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(textMarshalerType) {
+		v = v.Addr()
+	}
+	if !v.Type().Implements(textMarshalerType) || isJSONMarshaler(v) {
+		return "", false, nil
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "", false, nil
+	}
+	b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+	if err != nil {
+		return "", true, &MarshalerError{v.Type(), err}
+	}
+	return string(b), true, nil
+}
+
+// object writes the object v at level lvl with its members written by
+// members.
+func (e *encoder) object(v reflect.Value, lvl int, members func(reflect.Value, int) int) {
+	e.buf.WriteByte('{')
+	if members(v, lvl+1) == 0 {
+		e.buf.WriteByte('}')
+		return
+	}
+	e.newline(lvl)
+	e.buf.WriteByte('}')
+}
+
+// array writes the array v at level lvl. Arrays of scalar values are
+// written on one line, other arrays have one value per line.
+func (e *encoder) array(v reflect.Value, lvl int) {
+	n := v.Len()
+	oneLine := true
+	for i := 0; i < n && oneLine; i++ {
+		oneLine = isScalar(v.Index(i))
+		if _, nl := e.multiline(v.Index(i)); nl != "" {
+			oneLine = false
+		}
+	}
+	e.buf.WriteByte('[')
+	for i := 0; i < n; i++ {
+		if oneLine {
+			if i > 0 {
+				e.buf.WriteString(", ")
+			}
+			e.value(v.Index(i), lvl+1)
+			continue
+		}
+		if i > 0 && e.commas {
+			e.buf.WriteByte(',')
+		}
+		e.newline(lvl + 1)
+		if s, nl := e.multiline(v.Index(i)); nl != "" {
+			e.multilineString(s, nl, lvl+1)
+		} else {
+			e.value(v.Index(i), lvl+1)
+		}
+	}
+	if !oneLine {
+		e.newline(lvl)
+	}
+	e.buf.WriteByte(']')
+}
+
+// isScalar returns true if v is not a non empty object or array.
+func isScalar(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return len(cachedFields(v.Type())) == 0
+	case reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Slice:
+		return v.Len() == 0 || v.Type().Elem().Kind() == reflect.Uint8
+	}
+	return true
+}
+
+func (e *encoder) float(v reflect.Value) {
+	bits := v.Type().Bits()
+	f := v.Float()
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		e.setError(&UnsupportedValueError{v, strconv.FormatFloat(f, 'g', -1, bits)})
+		return
+	}
+	e.buf.Write(appendFloat(nil, f, bits))
+}
+
+// string writes s as a quoteless string when possible, or as a quoted
+// string.
+func (e *encoder) string(s string) {
+	if e.quote == Quoteless && isQuoteless(s, false) {
+		e.buf.WriteString(s)
+	} else {
+		e.quoted(s)
+	}
+}
+
+// quoted writes s as a single quoted string when e.quote is SingleQuoted,
+// or as a double quoted string.
+func (e *encoder) quoted(s string) {
+	q := quote(s)
+	if e.quote != SingleQuoted {
+		e.buf.WriteString(q)
+		return
+	}
+	e.buf.WriteByte('\'')
+	for i := 1; i < len(q)-1; i++ {
+		switch {
+		case q[i] == '\\' && q[i+1] == '"':
+			i++
+			e.buf.WriteByte('"')
+		case q[i] == '\\':
+			i++
+			e.buf.WriteByte('\\')
+			e.buf.WriteByte(q[i])
+		case q[i] == '\'':
+			e.buf.WriteString("\\'")
+		default:
+			e.buf.WriteByte(q[i])
+		}
+	}
+	e.buf.WriteByte('\'')
+}
+
+// multiline returns the string of v and its newline, "\n" or "\r\n", when
+// it is written as a multiline string. Otherwise it returns an empty newline.
+func (e *encoder) multiline(v reflect.Value) (string, string) {
+	if e.quote != Quoteless {
+		return "", ""
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", ""
+		}
+		v = v.Elem()
+	}
+	var s string
+	if t, ok, err := textValue(v); ok {
+		if err != nil {
+			return "", ""
+		}
+		s = t
+	} else if v.Kind() == reflect.String && v.Type() != numberType && !isJSONMarshaler(v) {
+		s = v.String()
+	} else {
+		return "", ""
+	}
+	return s, multilineNewline(s)
+}
+
+// multilineNewline returns the newline of s, "\n" or "\r\n", when s can be
+// written as a multiline string. It returns an empty string otherwise.
+func multilineNewline(s string) string {
+	lf := strings.Count(s, "\n")
+	if lf == 0 || !utf8.ValidString(s) {
+		return ""
+	}
+	crlf := strings.Count(s, "\r\n")
+	if crlf != 0 && crlf != lf {
+		return ""
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 && c != '\t' && c != '\n' && (c != '\r' || crlf == 0 || i+1 == len(s) || s[i+1] != '\n') {
+			return ""
+		}
+	}
+	if crlf != 0 {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// multilineString writes s as a multiline string with newline nl at
+// level lvl. Requires that the current line is empty up to the margin.
+func (e *encoder) multilineString(s, nl string, lvl int) {
+	if nl == "\r\n" {
+		e.buf.WriteString("`\\r\\n")
+		s = strings.ReplaceAll(s, "\r\n", "\n")
+	} else {
+		e.buf.WriteString("`\\n")
+	}
+	s = strings.ReplaceAll(s, "`", "`\\")
+	for _, line := range strings.Split(s, "\n") {
+		e.newline(lvl)
+		e.buf.WriteString(line)
+	}
+	e.buf.WriteByte('`')
+}
+
+// isQuoteless returns true if s is read back as the same quoteless string.
+// Member names are not checked against literals and number expressions.
+func isQuoteless(s string, name bool) bool {
+	if s == "" || !utf8.ValidString(s) {
+		return false
+	}
+	sc := NewScanner([]byte(s), 0)
+	if t := sc.Scan(); t.Kind != TokenQuotelessString || string(t.Raw) != s {
+		return false
+	}
+	if sc.Scan().Kind != TokenEOF {
+		return false
+	}
+	return name || (isLiteralValue([]byte(s)) == "" && !isNumberExpr([]byte(s)))
+}
+
+// quoteName returns name as a member name, quoteless when possible.
+func quoteName(name string) string {
+	if isQuoteless(name, true) {
+		return name
+	}
+	return quote(name)
+}
+
+// quote returns s as a double quoted string.
+func quote(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// isEmptyValue returns true if v is empty as defined by encoding/json for
+// the omitempty option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
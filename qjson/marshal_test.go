@@ -0,0 +1,149 @@
+package qjson
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testMarshal struct {
+	Name     string            `qjson:"name"`
+	Empty    string            `qjson:"empty,omitempty"`
+	Port     int               `qjson:"port"`
+	Ratio    float64           `qjson:"ratio"`
+	Hosts    []string          `qjson:"hosts"`
+	Labels   map[string]string `qjson:"labels"`
+	Text     string            `qjson:"text"`
+	Next     *testMarshal      `qjson:"next,omitempty"`
+	Items    []testItem        `qjson:"items"`
+	Time     time.Time         `qjson:"time"`
+	Raw      []byte            `qjson:"raw"`
+	Nil      interface{}       `qjson:"nil"`
+	Skipped  bool              `qjson:"-"`
+	Special  string            `qjson:"special key"`
+	Literal  string            `qjson:"literal"`
+	Number   string            `qjson:"number"`
+	CRLF     string            `qjson:"crlf"`
+	Backtick string            `qjson:"backtick"`
+}
+
+type testItem struct {
+	ID int `json:"id"`
+}
+
+func TestMarshal(t *testing.T) {
+	v := testMarshal{
+		Name:     "my server",
+		Port:     8080,
+		Ratio:    0.25,
+		Hosts:    []string{"a.example.com", "b, c"},
+		Labels:   map[string]string{"zone": "eu", "env": "prod"},
+		Text:     "line 1\n\n  line 2\n",
+		Next:     &testMarshal{Name: "next"},
+		Items:    []testItem{{ID: 1}, {ID: 2}},
+		Time:     time.Date(2020, 12, 23, 15, 40, 5, 0, time.UTC),
+		Raw:      []byte("raw"),
+		Special:  "# not a comment",
+		Literal:  "yes",
+		Number:   "0x10",
+		CRLF:     "a\r\nb",
+		Backtick: "a `b`\n",
+	}
+	exp := "name: my server\n" +
+		"port: 8080\n" +
+		"ratio: 0.25\n" +
+		"hosts: [a.example.com, \"b, c\"]\n" +
+		"labels: {\n  env: prod\n  zone: eu\n}\n" +
+		"text:\n  `\\n\n  line 1\n  \n    line 2\n  `\n" +
+		"next: {\n" +
+		"  name: next\n  port: 0\n  ratio: 0\n  hosts: null\n  labels: null\n  text: \"\"\n  items: null\n" +
+		"  time: \"0001-01-01T00:00:00Z\"\n  raw: null\n  nil: null\n  special key: \"\"\n  literal: \"\"\n  number: \"\"\n" +
+		"  crlf: \"\"\n  backtick: \"\"\n" +
+		"}\n" +
+		"items: [\n  {\n    id: 1\n  }\n  {\n    id: 2\n  }\n]\n" +
+		"time: \"2020-12-23T15:40:05Z\"\n" +
+		"raw: cmF3\n" +
+		"nil: null\n" +
+		"special key: \"# not a comment\"\n" +
+		"literal: \"yes\"\n" +
+		"number: \"0x10\"\n" +
+		"crlf:\n  `\\r\\n\n  a\n  b`\n" +
+		"backtick:\n  `\\n\n  a `\\b`\\\n  `\n"
+	out, err := Marshal(&v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != exp {
+		t.Fatalf("expected\n%s\ngot\n%s", exp, out)
+	}
+	var res testMarshal
+	if err := Unmarshal(out, &res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(res, v) {
+		t.Fatalf("expected %+v, got %+v", v, res)
+	}
+
+	out, err = MarshalIndent(map[string]interface{}{"a": []interface{}{1, "x\ny"}, "b": map[string]int{}}, "\t", "\t")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exp := "\ta: [\n\t\t1\n\t\t`\\n\n\t\tx\n\t\ty`\n\t]\n\tb: {}\n"; string(out) != exp {
+		t.Fatalf("expected %q, got %q", exp, out)
+	}
+	if out, err := Marshal(struct{}{}); err != nil || len(out) != 0 {
+		t.Fatalf("expected empty text, got %q, %v", out, err)
+	}
+}
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalText() ([]byte, error) {
+	return nil, errors.New("failed")
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		// 0
+		{v: 12, err: "qjson: unsupported type: int"},
+		{v: nil, err: "qjson: unsupported type: nil"},
+		{v: map[string]interface{}{"f": math.Inf(1)}, err: "qjson: unsupported value: +Inf"},
+		{v: map[string]interface{}{"c": make(chan int)}, err: "qjson: unsupported type: chan int"},
+		{v: map[string]interface{}{"m": failingMarshaler{}}, err: "qjson: error calling marshal method for type qjson.failingMarshaler: failed"},
+		// 5
+		{v: map[bool]int{true: 1}, err: "qjson: unsupported type: bool"},
+	}
+	for i, test := range tests {
+		_, err := Marshal(test.v)
+		if e2s(err) != test.err {
+			t.Fatalf("%d expected error %q, got %q", i, test.err, e2s(err))
+		}
+	}
+}
+
+func TestMarshalTrailingBackslash(t *testing.T) {
+	in := map[string]string{`C:\dir\`: `C:\dir\`, "a": `\`, `\`: `x\\`}
+	for _, quote := range []QuoteKind{DoubleQuoted, SingleQuoted} {
+		qjsonText, err := FromJSON([]byte(`{"C:\\dir\\":"C:\\dir\\","a":"\\","\\":"x\\\\"}`), &FromJSONOptions{Quote: quote})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var out map[string]string
+		if err := Unmarshal(qjsonText, &out); err != nil || !reflect.DeepEqual(out, in) {
+			t.Fatalf("expected %v, got %v, %v for %s", in, out, err, qjsonText)
+		}
+	}
+	qjsonText, err := Marshal(in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out map[string]string
+	if err := Unmarshal(qjsonText, &out); err != nil || !reflect.DeepEqual(out, in) {
+		t.Fatalf("expected %v, got %v, %v for %s", in, out, err, qjsonText)
+	}
+}
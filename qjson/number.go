@@ -2,13 +2,17 @@ package qjson
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"strconv"
 )
 
 // inspired by https://eli.thegreenplace.net/2010/01/02/top-down-operator-precedence-parsing
 
 // operator precedence
-// 3             w  d  h  m  s
+// 4             w  d  h  m  s  KB  MB  GB  TB  KiB  MiB  GiB  TiB
+// 3             **  ^^
 // 2             *  /  %  <<  >>  &  &^
 // 1             +  -  |  ^  ~
 // 0
@@ -26,6 +30,11 @@ var precedenceTable = [256]byte{
 	1, // tagOr
 	1, // tagInverse
 	2, // tagModulo
+	2, // tagShiftLeft
+	2, // tagShiftRight
+	2, // tagAndNot
+	3, // tagPower
+	0, // tagIdentifier
 	0, // tagOpenParen
 	0, // tagCloseParen
 	4, // tagWeeks
@@ -33,6 +42,7 @@ var precedenceTable = [256]byte{
 	4, // tagHours
 	4, // tagMinutes
 	4, // tagSeconds
+	4, // tagSize
 }
 
 const highestPrecedence = 4
@@ -68,6 +78,11 @@ func init() {
 		nil,           // tagOr
 		nudInverse,    // tagInverse
 		nil,           // tagModulo
+		nil,           // tagShiftLeft
+		nil,           // tagShiftRight
+		nil,           // tagAndNot
+		nil,           // tagPower
+		nudIdentifier, // tagIdentifier
 		nudOpenParen,  // tagOpenParen
 		nudCloseParen, // tagCloseParen
 		nil,           // tagWeeks
@@ -75,6 +90,7 @@ func init() {
 		nil,           // tagHours
 		nil,           // tagMinutes
 		nil,           // tagSeconds
+		nil,           // tagSize
 	}
 	ledTable = [256]ledFunc{
 		nil,               // tagUnknown
@@ -90,6 +106,11 @@ func init() {
 		ledOr,             // tagOr
 		nil,               // tagInverse
 		ledModulo,         // tagModulo
+		ledShiftLeft,      // tagShiftLeft
+		ledShiftRight,     // tagShiftRight
+		ledAndNot,         // tagAndNot
+		ledPower,          // tagPower
+		nil,               // tagIdentifier
 		nil,               // tagOpenParen
 		nil,               // tagCloseParen
 		ledWeeks,          // tagWeeks
@@ -97,6 +118,7 @@ func init() {
 		ledHours,          // tagHours
 		ledMinutes,        // tagMinutes
 		ledSeconds,        // tagSeconds
+		ledSize,           // tagSize
 	}
 }
 
@@ -137,43 +159,228 @@ func (tk *numTokenizer) expression(rbp byte) interface{} {
 	return left
 }
 
-// evalNumberExpression evaluates the expression in input and
-// return the resulting value, otherwise reture the error and
-// its index in the input.
-func evalNumberExpression(input []byte) (float64, int, error) {
+// evalNumberExpression evaluates the expression in input with the
+// decoding options opts and return the resulting int, uint64 or float64
+// value, or *big.Rat value with exact decimals, otherwise reture the error
+// and its index in the input. The use of a disabled extension is an error.
+// The warnings are reported to warn, when not nil, with their index.
+func evalNumberExpression(input []byte, opts *DecodeOptions, warn func(msg string, i int)) (interface{}, int, error) {
 	var tk numTokenizer
 	tk.init(input)
+	tk.disabled = opts.Disabled
+	tk.exact = opts.DecimalPlaces > 0
+	tk.functions, tk.constants = opts.Functions, opts.Constants
+	tk.overflow, tk.warn = opts.IntegerOverflow, warn
 	tk.nextToken()
 	res := tk.expression(0)
 	if tk.tk.tag == tagError {
 		if tk.tk.val.(error) != ErrEndOfInput {
-			return 0, tk.tk.pos, tk.tk.val.(error)
+			return nil, tk.tk.pos, tk.tk.val.(error)
 		}
 	} else {
 		if tk.tk.tag == tagCloseParen {
-			return 0, tk.tk.pos, ErrUnopenedParenthesis
+			return nil, tk.tk.pos, ErrUnopenedParenthesis
 		}
-		return 0, tk.tk.pos, ErrInvalidNumericExpression
+		return nil, tk.tk.pos, ErrInvalidNumericExpression
 	}
-	switch res.(type) {
-	case int:
-		return float64(res.(int)), 0, nil
+	switch x := res.(type) {
+	case nil:
+		return nil, tk.tk.pos, tk.tk.val.(error)
 	case float64:
-		return res.(float64), 0, nil
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return nil, 0, ErrNumberOverflow
+		}
+		if tk.exact {
+			res = new(big.Rat).SetFloat64(x)
+		}
+	}
+	if r, ok := res.(*big.Rat); ok {
+		if n := decimalDigits(r); n < 0 || n > opts.DecimalPlaces {
+			return nil, 0, ErrInexactDecimal
+		}
+	}
+	return res, 0, nil
+}
+
+// decimalDigits returns the number of fractional digits of the decimal
+// text of r, or -1 if the decimal text of r is infinite.
+func decimalDigits(r *big.Rat) int {
+	// r is a finite decimal when its denominator is 2^twos * 5^fives
+	d := new(big.Int).Set(r.Denom())
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+	var fives int
+	five, m := big.NewInt(5), new(big.Int)
+	for d.Cmp(big.NewInt(1)) != 0 {
+		if d.QuoRem(d, five, m); m.Sign() != 0 {
+			return -1
+		}
+		fives++
+	}
+	if twos > fives {
+		return twos
+	}
+	return fives
+}
+
+// formatNumber returns the JSON text of the value v of a number expression.
+// The integers are written exactly, and the floats with the shortest text
+// parsed back to the same float64.
+func formatNumber(v interface{}) string {
+	switch x := v.(type) {
+	case int:
+		return strconv.Itoa(x)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case *big.Rat:
+		return x.FloatString(decimalDigits(x))
+	}
+	return string(appendFloat(nil, v.(float64), 64))
+}
+
+// appendFloat appends to b the shortest text of f parsed back to the same
+// float of the given bits size, in the same format as encoding/json.
+func appendFloat(b []byte, f float64, bits int) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	start := len(b)
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if n := len(b); format == 'e' && n-start >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+		// e-07 is written e-7
+		b[n-2] = b[n-1]
+		b = b[:n-1]
+	}
+	return b
+}
+
+// isJSONNumber returns true if p is a number literal valid in JSON.
+func isJSONNumber(p []byte) bool {
+	i := 0
+	if i < len(p) && p[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(p) && p[i] == '0':
+		i++
+	case i < len(p) && inRange(p[i], '1', '9'):
+		for i++; i < len(p) && isIntDigit(p[i]); i++ {
+		}
+	default:
+		return false
+	}
+	if i < len(p) && p[i] == '.' {
+		i++
+		if i == len(p) || !isIntDigit(p[i]) {
+			return false
+		}
+		for i++; i < len(p) && isIntDigit(p[i]); i++ {
+		}
+	}
+	if i < len(p) && (p[i] == 'e' || p[i] == 'E') {
+		i++
+		if i < len(p) && (p[i] == '+' || p[i] == '-') {
+			i++
+		}
+		if i == len(p) || !isIntDigit(p[i]) {
+			return false
+		}
+		for i++; i < len(p) && isIntDigit(p[i]); i++ {
+		}
+	}
+	return i == len(p)
+}
+
+// isInteger returns true if v is an int or an uint64.
+func isInteger(v interface{}) bool {
+	switch v.(type) {
+	case int, uint64:
+		return true
 	}
-	return 0, tk.tk.pos, tk.tk.val.(error)
+	return false
 }
 
-// normalizeTypes ensures that v1 anv v2 are both int, otherwise cast both to float64.
-// Requires that v1 anv v2 are int or float64.
+// bigInt returns the int or uint64 value v as a *big.Int.
+func bigInt(v interface{}) *big.Int {
+	if x, ok := v.(uint64); ok {
+		return new(big.Int).SetUint64(x)
+	}
+	return big.NewInt(int64(v.(int)))
+}
+
+// intResult returns the integer result z of the operator t as an int or
+// an uint64 when it is in their range. Otherwise the overflow is handled
+// by intOverflow.
+func (tk *numTokenizer) intResult(t numToken, z *big.Int) interface{} {
+	if z.IsInt64() {
+		return int(z.Int64())
+	}
+	if z.IsUint64() {
+		return z.Uint64()
+	}
+	f, _ := new(big.Float).SetInt(z).Float64()
+	return tk.intOverflow(t, f)
+}
+
+// intOverflow handles the overflow of the integer result of the operator
+// t with the float64 value f. With OverflowFloat, it returns f and reports
+// a warning. Otherwise, it sets the ErrNumberOverflow error at t and
+// returns nil.
+func (tk *numTokenizer) intOverflow(t numToken, f float64) interface{} {
+	if tk.overflow == OverflowFloat {
+		if tk.warn != nil {
+			tk.warn("integer overflow converted to float", t.pos)
+		}
+		return f
+	}
+	tk.setErrorAndPos(ErrNumberOverflow, t.pos)
+	return nil
+}
+
+// intValue returns u as an int when it is not above the maximum int.
+// The integers above are uint64.
+func intValue(u uint64) interface{} {
+	if u <= math.MaxInt64 {
+		return int(u)
+	}
+	return u
+}
+
+// normalizeTypes ensures that v1 anv v2 are both int or both uint64,
+// otherwise cast both to float64. An int combined with an uint64 is
+// converted to uint64. A *big.Rat combined with another type converts it
+// to *big.Rat. Requires that v1 anv v2 are int, uint64, float64 or *big.Rat.
 func normalizeTypes(v1 interface{}, v2 interface{}) (interface{}, interface{}) {
-	if v1Int, ok := v1.(int); ok {
-		if v2Float, ok := v2.(float64); ok {
-			return float64(v1Int), v2Float
+	if _, ok := v1.(*big.Rat); ok {
+		return v1, toRat(v2)
+	}
+	if _, ok := v2.(*big.Rat); ok {
+		return toRat(v1), v2
+	}
+	switch x1 := v1.(type) {
+	case int:
+		switch x2 := v2.(type) {
+		case uint64:
+			return uint64(x1), x2
+		case float64:
+			return float64(x1), x2
 		}
-	} else if v1Float, ok := v1.(float64); ok {
-		if v2Int, ok := v2.(int); ok {
-			return v1Float, float64(v2Int)
+	case uint64:
+		switch x2 := v2.(type) {
+		case int:
+			return x1, uint64(x2)
+		case float64:
+			return float64(x1), x2
+		}
+	case float64:
+		switch x2 := v2.(type) {
+		case int:
+			return x1, float64(x2)
+		case uint64:
+			return x1, float64(x2)
 		}
 	}
 	return v1, v2
@@ -202,9 +409,12 @@ func ledPlus(tk *numTokenizer, t numToken, left interface{}) interface{} {
 		}
 		return nil
 	}
+	if isInteger(left) && isInteger(right) {
+		return tk.intResult(t, new(big.Int).Add(bigInt(left), bigInt(right)))
+	}
 	left, right = normalizeTypes(left, right)
-	if x, ok := left.(int); ok {
-		return x + right.(int)
+	if x, ok := left.(*big.Rat); ok {
+		return new(big.Rat).Add(x, right.(*big.Rat))
 	}
 	return left.(float64) + right.(float64)
 }
@@ -216,8 +426,10 @@ func nudMinus(tk *numTokenizer, t numToken) interface{} {
 		if tk.tk.val.(error) == ErrEndOfInput {
 			tk.setErrorAndPos(ErrInvalidNumericExpression, t.pos)
 		}
-	case int:
-		return -right.(int)
+	case int, uint64:
+		return tk.intResult(t, new(big.Int).Neg(bigInt(right)))
+	case *big.Rat:
+		return new(big.Rat).Neg(right.(*big.Rat))
 	case float64:
 		return -right.(float64)
 	}
@@ -232,9 +444,12 @@ func ledMinus(tk *numTokenizer, t numToken, left interface{}) interface{} {
 		}
 		return nil
 	}
+	if isInteger(left) && isInteger(right) {
+		return tk.intResult(t, new(big.Int).Sub(bigInt(left), bigInt(right)))
+	}
 	left, right = normalizeTypes(left, right)
-	if x, ok := left.(int); ok {
-		return x - right.(int)
+	if x, ok := left.(*big.Rat); ok {
+		return new(big.Rat).Sub(x, right.(*big.Rat))
 	}
 	return left.(float64) - right.(float64)
 }
@@ -247,9 +462,12 @@ func ledMultiplication(tk *numTokenizer, t numToken, left interface{}) interface
 		}
 		return nil
 	}
+	if isInteger(left) && isInteger(right) {
+		return tk.intResult(t, new(big.Int).Mul(bigInt(left), bigInt(right)))
+	}
 	left, right = normalizeTypes(left, right)
-	if x, ok := left.(int); ok {
-		return x * right.(int)
+	if x, ok := left.(*big.Rat); ok {
+		return new(big.Rat).Mul(x, right.(*big.Rat))
 	}
 	return left.(float64) * right.(float64)
 }
@@ -262,14 +480,29 @@ func ledDivision(tk *numTokenizer, t numToken, left interface{}) interface{} {
 		}
 		return nil
 	}
+	if isInteger(left) && isInteger(right) {
+		x2 := bigInt(right)
+		if x2.Sign() == 0 {
+			tk.setErrorAndPos(ErrDivisionByZero, t.pos)
+			return nil
+		}
+		if tk.exact {
+			// the exact division of integers is not truncated
+			if r := new(big.Rat).SetFrac(bigInt(left), x2); !r.IsInt() {
+				return r
+			}
+		}
+		return tk.intResult(t, x2.Quo(bigInt(left), x2))
+	}
 	left, right = normalizeTypes(left, right)
-	if x1, ok := left.(int); ok {
-		x2 := right.(int)
-		if x2 == 0 {
+	switch x1 := left.(type) {
+	case *big.Rat:
+		x2 := right.(*big.Rat)
+		if x2.Sign() == 0 {
 			tk.setErrorAndPos(ErrDivisionByZero, t.pos)
 			return nil
 		}
-		return x1 / right.(int)
+		return new(big.Rat).Quo(x1, x2)
 	}
 	x2 := right.(float64)
 	if x2 == 0 {
@@ -309,7 +542,9 @@ func nudInverse(tk *numTokenizer, t numToken) interface{} {
 		}
 	case int:
 		return ^right.(int)
-	case float64:
+	case uint64:
+		return intValue(^right.(uint64))
+	case float64, *big.Rat:
 		tk.setErrorAndPos(ErrOperandMustBeInteger, t.pos)
 		return nil
 	}
@@ -324,17 +559,16 @@ func ledModulo(tk *numTokenizer, t numToken, left interface{}) interface{} {
 		}
 		return nil
 	}
-	left, right = normalizeTypes(left, right)
-	if x1, ok := left.(int); ok {
-		x2 := right.(int)
-		if x2 == 0 {
-			tk.setErrorAndPos(ErrDivisionByZero, t.pos)
-			return nil
-		}
-		return x1 % x2
+	if !isInteger(left) || !isInteger(right) {
+		tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
+		return nil
 	}
-	tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
-	return nil
+	x2 := bigInt(right)
+	if x2.Sign() == 0 {
+		tk.setErrorAndPos(ErrDivisionByZero, t.pos)
+		return nil
+	}
+	return tk.intResult(t, x2.Rem(bigInt(left), x2))
 }
 
 func ledAnd(tk *numTokenizer, t numToken, left interface{}) interface{} {
@@ -346,8 +580,11 @@ func ledAnd(tk *numTokenizer, t numToken, left interface{}) interface{} {
 		return nil
 	}
 	left, right = normalizeTypes(left, right)
-	if x, ok := left.(int); ok {
+	switch x := left.(type) {
+	case int:
 		return x & right.(int)
+	case uint64:
+		return intValue(x & right.(uint64))
 	}
 	tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
 	return nil
@@ -362,8 +599,11 @@ func ledOr(tk *numTokenizer, t numToken, left interface{}) interface{} {
 		return nil
 	}
 	left, right = normalizeTypes(left, right)
-	if x, ok := left.(int); ok {
+	switch x := left.(type) {
+	case int:
 		return x | right.(int)
+	case uint64:
+		return intValue(x | right.(uint64))
 	}
 	tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
 	return nil
@@ -378,17 +618,115 @@ func ledXor(tk *numTokenizer, t numToken, left interface{}) interface{} {
 		return nil
 	}
 	left, right = normalizeTypes(left, right)
-	if x, ok := left.(int); ok {
+	switch x := left.(type) {
+	case int:
 		return x ^ right.(int)
+	case uint64:
+		return intValue(x ^ right.(uint64))
+	}
+	tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
+	return nil
+}
+
+func ledAndNot(tk *numTokenizer, t numToken, left interface{}) interface{} {
+	right := tk.expression(precedenceTable[tagAndNot])
+	if right == nil {
+		if tk.tk.val.(error) == ErrEndOfInput {
+			tk.setErrorAndPos(ErrInvalidNumericExpression, t.pos)
+		}
+		return nil
+	}
+	left, right = normalizeTypes(left, right)
+	switch x := left.(type) {
+	case int:
+		return x &^ right.(int)
+	case uint64:
+		return intValue(x &^ right.(uint64))
 	}
 	tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
 	return nil
 }
 
+func ledPower(tk *numTokenizer, t numToken, left interface{}) interface{} {
+	// the power operator is right associative
+	right := tk.expression(precedenceTable[tagPower] - 1)
+	if right == nil {
+		if tk.tk.val.(error) == ErrEndOfInput {
+			tk.setErrorAndPos(ErrInvalidNumericExpression, t.pos)
+		}
+		return nil
+	}
+	return tk.power(t, left, right)
+}
+
+func ledShiftLeft(tk *numTokenizer, t numToken, left interface{}) interface{} {
+	n, ok := tk.shiftCount(t, left)
+	if !ok {
+		return nil
+	}
+	x := bigInt(left)
+	if n >= 128 && x.Sign() != 0 {
+		// the result is out of the uint64 range, and infinite when the
+		// shift count exceeds the float64 exponent range
+		if n > 2048 {
+			n = 2048
+		}
+		return tk.intOverflow(t, math.Ldexp(toFloat64(left), int(n)))
+	}
+	return tk.intResult(t, x.Lsh(x, uint(n)))
+}
+
+func ledShiftRight(tk *numTokenizer, t numToken, left interface{}) interface{} {
+	n, ok := tk.shiftCount(t, left)
+	if !ok {
+		return nil
+	}
+	if x, ok := left.(uint64); ok {
+		return intValue(x >> n)
+	}
+	return left.(int) >> n
+}
+
+// shiftCount evaluates the right operand of the shift operator t and
+// returns the shift count. It returns false when an error occured, or
+// when left or the shift count is not an integer.
+func (tk *numTokenizer) shiftCount(t numToken, left interface{}) (uint64, bool) {
+	right := tk.expression(precedenceTable[t.tag])
+	if right == nil {
+		if tk.tk.val.(error) == ErrEndOfInput {
+			tk.setErrorAndPos(ErrInvalidNumericExpression, t.pos)
+		}
+		return 0, false
+	}
+	switch left.(type) {
+	case int, uint64:
+	default:
+		tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
+		return 0, false
+	}
+	switch x := right.(type) {
+	case int:
+		if x < 0 {
+			tk.setErrorAndPos(ErrNegativeShiftCount, t.pos)
+			return 0, false
+		}
+		return uint64(x), true
+	case uint64:
+		return x, true
+	}
+	tk.setErrorAndPos(ErrOperandsMustBeInteger, t.pos)
+	return 0, false
+}
+
 func toFloat64(v interface{}) float64 {
 	switch v.(type) {
 	case int:
 		return float64(v.(int))
+	case uint64:
+		return float64(v.(uint64))
+	case *big.Rat:
+		f, _ := v.(*big.Rat).Float64()
+		return f
 	case float64:
 		return v.(float64)
 	case nil:
@@ -398,13 +736,28 @@ func toFloat64(v interface{}) float64 {
 	}
 }
 
+// toRat returns the int, uint64, float64 or *big.Rat value v as a *big.Rat.
+func toRat(v interface{}) *big.Rat {
+	switch x := v.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(x))
+	case uint64:
+		return new(big.Rat).SetUint64(x)
+	case float64:
+		return new(big.Rat).SetFloat64(x)
+	}
+	return v.(*big.Rat)
+}
+
 func ledWeeks(tk *numTokenizer, t numToken, left interface{}) interface{} {
 	const duration float64 = 3600 * 24 * 7
 	leftFloat := toFloat64(left)
 	if tk.tk.tag == tagCloseParen {
 		return leftFloat * duration
 	}
+	tk.duration = true
 	right := tk.expression(precedenceTable[tagWeeks] - 1)
+	tk.duration = false
 	if right == nil { // right hand operand is optional
 		if tk.tk.val.(error) == ErrEndOfInput {
 			return leftFloat * duration
@@ -420,7 +773,9 @@ func ledDays(tk *numTokenizer, t numToken, left interface{}) interface{} {
 	if tk.tk.tag == tagCloseParen {
 		return leftFloat * duration
 	}
+	tk.duration = true
 	right := tk.expression(precedenceTable[tagDays] - 1)
+	tk.duration = false
 	if right == nil { // right hand operand is optional
 		if tk.tk.val.(error) == ErrEndOfInput {
 			return leftFloat * duration
@@ -436,7 +791,9 @@ func ledHours(tk *numTokenizer, t numToken, left interface{}) interface{} {
 	if tk.tk.tag == tagCloseParen {
 		return leftFloat * duration
 	}
+	tk.duration = true
 	right := tk.expression(precedenceTable[tagHours] - 1)
+	tk.duration = false
 	if right == nil { // right hand operand is optional
 		if tk.tk.val.(error) == ErrEndOfInput {
 			return leftFloat * duration
@@ -452,7 +809,9 @@ func ledMinutes(tk *numTokenizer, t numToken, left interface{}) interface{} {
 	if tk.tk.tag == tagCloseParen {
 		return leftFloat * duration
 	}
+	tk.duration = true
 	right := tk.expression(precedenceTable[tagMinutes] - 1)
+	tk.duration = false
 	if right == nil { // right hand operand is optional
 		if tk.tk.val.(error) == ErrEndOfInput {
 			return leftFloat * duration
@@ -467,7 +826,9 @@ func ledSeconds(tk *numTokenizer, t numToken, left interface{}) interface{} {
 	if tk.tk.tag == tagCloseParen {
 		return leftFloat
 	}
+	tk.duration = true
 	right := tk.expression(precedenceTable[tagSeconds] - 1)
+	tk.duration = false
 	if right == nil { // right hand operand is optional
 		if tk.tk.val.(error) == ErrEndOfInput {
 			return leftFloat
@@ -476,3 +837,46 @@ func ledSeconds(tk *numTokenizer, t numToken, left interface{}) interface{} {
 	}
 	return leftFloat + toFloat64(right)
 }
+
+// sizeUnits are the multipliers of the size suffixes.
+var sizeUnits = map[string]uint64{
+	"KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12,
+	"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40,
+}
+
+// ledSize returns the number of bytes of the size left with the unit t.
+// A size with a fraction of byte is an error. The unit ends its operand,
+// so that it can't be followed or preceded by another unit.
+func ledSize(tk *numTokenizer, t numToken, left interface{}) interface{} {
+	if tk.duration {
+		tk.setErrorAndPos(ErrInvalidNumericExpression, t.pos)
+		return nil
+	}
+	if next := tk.token(); next.tag >= tagWeeks && next.tag <= tagSize {
+		tk.setErrorAndPos(ErrInvalidNumericExpression, next.pos)
+		return nil
+	}
+	unit := t.val.(uint64)
+	var r *big.Rat
+	switch x := left.(type) {
+	case int, uint64:
+		return tk.intResult(t, new(big.Int).Mul(bigInt(x), new(big.Int).SetUint64(unit)))
+	case *big.Rat:
+		r = new(big.Rat).Set(x)
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return tk.intOverflow(t, x)
+		}
+		// the shortest decimal text of x is the number as written, like 1.1
+		r, _ = new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64))
+	}
+	r.Mul(r, new(big.Rat).SetUint64(unit))
+	if !r.IsInt() {
+		tk.setErrorAndPos(ErrFractionalSize, t.pos)
+		return nil
+	}
+	if tk.exact {
+		return bigIntValue(r.Num())
+	}
+	return tk.intResult(t, r.Num())
+}
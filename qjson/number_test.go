@@ -46,7 +46,7 @@ func TestNumberEval(t *testing.T) {
 		{in: "", err: ErrEndOfInput},
 		{in: " ", err: ErrEndOfInput, pos: 1},
 		{in: "10", out: 10},
-		{in: "a", err: ErrInvalidNumericExpression},
+		{in: "a", err: ErrUnknownConstant},
 		{in: "2 + 5", out: 7},
 		// 5
 		{in: "2 + 5.3", out: 7.3},
@@ -77,7 +77,7 @@ func TestNumberEval(t *testing.T) {
 		{in: "10. | ", err: ErrInvalidNumericExpression, pos: 4},
 		{in: "10. ^ ", err: ErrInvalidNumericExpression, pos: 4},
 		{in: "& ", err: ErrInvalidNumericExpression, pos: 0},
-		{in: "10 ** ", err: ErrInvalidNumericExpression, pos: 4},
+		{in: "10 ** ", err: ErrInvalidNumericExpression, pos: 3},
 		// 30
 		{in: "10 + ", err: ErrInvalidNumericExpression, pos: 3},
 		{in: "10 * ", err: ErrInvalidNumericExpression, pos: 3},
@@ -117,21 +117,21 @@ func TestNumberEval(t *testing.T) {
 		// 60
 		{in: "2m", out: 120},
 		{in: "2m 15", out: 135},
-		{in: "2m a", err: ErrInvalidNumericExpression, pos: 3},
+		{in: "2m a", err: ErrUnknownConstant, pos: 3},
 		{in: "1h", out: 3600},
 		{in: "1h 10", out: 3610},
 		// 65
-		{in: "2h a", err: ErrInvalidNumericExpression, pos: 3},
+		{in: "2h a", err: ErrUnknownConstant, pos: 3},
 		{in: "1d", out: 86400},
 		{in: "1d 10", out: 86410},
-		{in: "2d a", err: ErrInvalidNumericExpression, pos: 3},
+		{in: "2d a", err: ErrUnknownConstant, pos: 3},
 		{in: "1w", out: 604800},
 		// 70
 		{in: "1w 10", out: 604810},
-		{in: "2w a", err: ErrInvalidNumericExpression, pos: 3},
+		{in: "2w a", err: ErrUnknownConstant, pos: 3},
 		{in: "1 s", out: 1},
 		{in: "1s 10", out: 11},
-		{in: "2s a", err: ErrInvalidNumericExpression, pos: 3},
+		{in: "2s a", err: ErrUnknownConstant, pos: 3},
 		// 75
 		{in: "6 7", err: ErrInvalidNumericExpression, pos: 2},
 		{in: "1.3 5h", err: ErrInvalidNumericExpression, pos: 4},
@@ -155,9 +155,86 @@ func TestNumberEval(t *testing.T) {
 		{in: "2020-12-23T15:40:05 + 2m", out: 1608738125},
 		{in: "2020-12-23T25:40:05", err: ErrInvalidISODateTime, pos: 0},
 		{in: "2020-12-23T15:40:60", err: ErrInvalidISODateTime, pos: 0},
+		{in: "1 << 20", out: 1 << 20},
+		// 95
+		{in: "1024 >> 3", out: 128},
+		{in: "1 + 1 << 4", out: 17},
+		{in: "2 * 3 << 1", out: 12},
+		{in: "0xff &^ 0x0f", out: 0xf0},
+		{in: "0xff & 0x0f", out: 0x0f},
+		// 100
+		{in: "1.5 << 2", err: ErrOperandsMustBeInteger, pos: 4},
+		{in: "1 << 2.", err: ErrOperandsMustBeInteger, pos: 2},
+		{in: "1 << -1", err: ErrNegativeShiftCount, pos: 2},
+		{in: "10. &^ 3", err: ErrOperandsMustBeInteger, pos: 4},
+		{in: "1 < 2", err: ErrInvalidNumericExpression, pos: 2},
+		// 105
+		{in: "1 << ", err: ErrInvalidNumericExpression, pos: 2},
+		{in: "1 &^ ", err: ErrInvalidNumericExpression, pos: 2},
+		{in: "2 ** 10", out: 1024},
+		{in: "2 ^^ 3 ** 2", out: 512},
+		{in: "3 * 2 ** 2", out: 12},
+		// 110
+		{in: "2 ** -1", out: 0.5},
+		{in: "4 ** 0.5", out: 2},
+		{in: "-8 ** 0.5", err: ErrNotANumber, pos: 3},
+		{in: "max(4, 2 * 3)", out: 6},
+		{in: "min(4, 2 * 3, -1.5)", out: -1.5},
+		// 115
+		{in: "abs(-3) + sqrt(16)", out: 7},
+		{in: "round(2.5) + floor(-1.5) + ceil(1.2)", out: 3},
+		{in: "1 + max(1, (2))", out: 3},
+		{in: "max()", err: ErrInvalidArgumentCount, pos: 0},
+		{in: "abs(1, 2)", err: ErrInvalidArgumentCount, pos: 0},
+		// 120
+		{in: "foo(1)", err: ErrUnknownFunction, pos: 0},
+		{in: "max(1, ", err: ErrInvalidNumericExpression, pos: 3},
+		{in: "max(1 2)", err: ErrUnclosedParenthesis, pos: 3},
+		{in: "max", err: ErrUnknownConstant, pos: 0},
+		{in: "sqrt(-1)", err: ErrNotANumber, pos: 0},
+		// 125
+		{in: "1, 2", err: ErrInvalidNumericExpression, pos: 1},
+		{in: "1h30m", out: 5400},
+		{in: "0x7FFF_FFFF_FFFF_FFFF + 1", out: 1 << 63},
+		{in: "0xFFFF_FFFF_FFFF_FFFF + 1", err: ErrNumberOverflow, pos: 22},
+		{in: "-0x7FFF_FFFF_FFFF_FFFF - 2", err: ErrNumberOverflow, pos: 23},
+		// 130
+		{in: "0x1_0000_0000 * 0x1_0000_0000", err: ErrNumberOverflow, pos: 14},
+		{in: "1 << 64", err: ErrNumberOverflow, pos: 2},
+		{in: "1 << 1000", err: ErrNumberOverflow, pos: 2},
+		{in: "10 ** 20", err: ErrNumberOverflow, pos: 3},
+		{in: "-(0xFFFF_FFFF_FFFF_FFFF)", err: ErrNumberOverflow, pos: 0},
+		// 135
+		{in: "-0x8000_0000_0000_0000 / -1", out: 1 << 63},
+		{in: "-7 % 0xFFFF_FFFF_FFFF_FFFF", out: -7},
+		{in: "0 << 1000", out: 0},
+		{in: "512KiB", out: 512 << 10},
+		{in: "1.5GB", out: 1.5e9},
+		// 140
+		{in: "4 MiB + 1", out: 4<<20 + 1},
+		{in: "2 * 64MiB", out: 128 << 20},
+		{in: "1.1KiB", err: ErrFractionalSize, pos: 3},
+		{in: "-1KB", out: -1000},
+		{in: "16777216TiB", err: ErrNumberOverflow, pos: 8},
+		// 145
+		{in: "1 Ki", err: ErrInvalidNumericExpression, pos: 2},
+		{in: "1KB 2", err: ErrInvalidNumericExpression, pos: 4},
+		{in: "1.1KB", out: 1100},
+		{in: "0.0001KB", err: ErrFractionalSize, pos: 6},
+		{in: "1.0005KB", err: ErrFractionalSize, pos: 6},
+		// 150
+		{in: "(1 / 3.)MB", err: ErrFractionalSize, pos: 8},
+		{in: "1e30TB", err: ErrNumberOverflow, pos: 4},
+		{in: "1KB KB", err: ErrInvalidNumericExpression, pos: 4},
+		{in: "1KB h", err: ErrInvalidNumericExpression, pos: 4},
+		{in: "1h 2KB", err: ErrInvalidNumericExpression, pos: 4},
 	}
 	for i, test := range tests {
-		out, pos, err := evalNumberExpression([]byte(test.in))
+		res, pos, err := evalNumberExpression([]byte(test.in), &DecodeOptions{}, nil)
+		var out float64
+		if res != nil {
+			out = toFloat64(res)
+		}
 		var hasErrors bool
 		if out != test.out {
 			hasErrors = true
@@ -177,3 +254,92 @@ func TestNumberEval(t *testing.T) {
 		}
 	}
 }
+
+func TestExactIntegers(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+		err string
+	}{
+		// 0
+		{in: "0xFFFF_FFFF_FFFF_FFFF", out: "18446744073709551615"},
+		{in: "9007199254740993", out: "9007199254740993"},
+		{in: "12345678901234567 * 10", out: "123456789012345670"},
+		{in: "0xFFFF_FFFF_FFFF_FFFF - 1", out: "18446744073709551614"},
+		{in: "0xFFFF_FFFF_FFFF_FFFF & 0xFF", out: "255"},
+		// 5
+		{in: "0 | ~0xFFFF_FFFF_FFFF_FF00", out: "255"},
+		{in: "-0x8000_0000_0000_0000", out: "-9223372036854775808"},
+		{in: "0x8000_0000_0000_0000 / 2", out: "4611686018427387904"},
+		{in: "0xFFFF_FFFF_FFFF_FFFF % 10", out: "5"},
+		{in: "1.5 * 2", out: "3"},
+		// 10
+		{in: "0x1_0000_0000_0000_0000", err: "number overflow at line 1 col 4"},
+		{in: "10 ** 19", out: "10000000000000000000"},
+		{in: "abs(-0x8000_0000_0000_0000)", out: "9223372036854775808"},
+		{in: "max(-1, 0xFFFF_FFFF_FFFF_FFFF)", out: "18446744073709551615"},
+		{in: "round(1e19)", out: "10000000000000000000"},
+		// 15
+		{in: "1.5TB", out: "1500000000000"},
+		{in: "(1 << 10) * 1GiB", out: "1099511627776"},
+	}
+	for i, test := range tests {
+		out, err := Decode([]byte("a: " + test.in))
+		if e2s(err) != test.err {
+			t.Errorf("%d expected error %q, got %q", i, test.err, e2s(err))
+		} else if exp := `{"a":` + test.out + `}`; err == nil && string(out) != exp {
+			t.Errorf("%d expected %s, got %s", i, exp, out)
+		}
+	}
+	var v struct{ A uint64 }
+	if err := Unmarshal([]byte("a: 0xFFFF_FFFF_FFFF_FFFF"), &v); err != nil || v.A != 1<<64-1 {
+		t.Fatalf("unexpected value %d, error %v", v.A, err)
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		in  interface{}
+		out string
+	}{
+		{in: 0.1, out: "0.1"},
+		{in: 1 / 3., out: "0.3333333333333333"},
+		{in: 1209600., out: "1209600"},
+		{in: 123456789012345680., out: "123456789012345680"},
+		{in: 1e21, out: "1e+21"},
+		{in: 1.5e-7, out: "1.5e-7"},
+		{in: -2.5e-10, out: "-2.5e-10"},
+		{in: 42, out: "42"},
+		{in: uint64(1 << 63), out: "9223372036854775808"},
+	}
+	for _, test := range tests {
+		if out := formatNumber(test.in); out != test.out {
+			t.Errorf("%v: expected %s, got %s", test.in, test.out, out)
+		}
+	}
+	if _, _, err := evalNumberExpression([]byte("1e300 * 1e300"), &DecodeOptions{}, nil); err != ErrNumberOverflow {
+		t.Fatalf("expected ErrNumberOverflow, got %v", err)
+	}
+}
+
+func TestFunctionCalls(t *testing.T) {
+	in := "a: max(4, 2 * 3), b: [min(1, 2), 3], c: (note, d: see below)"
+	exp := `{"a":6,"b":[1,3],"c":"(note","d":"see below)"}`
+	if out, err := Decode([]byte(in)); err != nil || string(out) != exp {
+		t.Fatalf("expected %s, got %s, %v", exp, out, err)
+	}
+	// texts that are not valid number expressions are quoteless strings
+	in = "a: round(ish) estimate, b: min(x), c: max(3 tries), d: [abs(x), 1], e: sqrt()"
+	exp = `{"a":"round(ish) estimate","b":"min(x)","c":"max(3 tries)","d":["abs(x)",1],"e":"sqrt()"}`
+	if out, err := Decode([]byte(in)); err != nil || string(out) != exp {
+		t.Fatalf("expected %s, got %s, %v", exp, out, err)
+	}
+	if _, err := Decode([]byte("a: min(1, 1/0)")); e2s(err) != "division by zero at line 1 col 12" {
+		t.Fatalf("expected division by zero error, got %v", err)
+	}
+	exp = `{"a":2.35}`
+	out, err := DecodeWithOptions([]byte("a: round(2.345 * 100) / 100."), DecodeOptions{DecimalPlaces: 2})
+	if err != nil || string(out) != exp {
+		t.Fatalf("expected %s, got %s, %v", exp, out, err)
+	}
+}
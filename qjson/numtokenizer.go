@@ -2,6 +2,8 @@ package qjson
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -10,25 +12,35 @@ import (
 
 // The tokenizer is used only for numbers and arithmetic operations.
 // The tokenizer input is a quoteless string. The output are the operators
-// "()+-*/%^|&~" and int and float values, or an error. The binary and
+// "()+-*/%^|&~,", "<<", ">>", "&^", "**" and "^^", the size suffixes, the
+// identifiers and int and float values, or an error. The binary and
 // hexadecimal numbers are converted into int by the tokenizer.
 //
 //
 // Expression evaluation:
 // int are implicitely cast into float when combined with a float in a
-// the operations "+-*/". The operations "^~|&%" require two ints and
-// output an int.
+// the operations "+-*/". The operations "^~|&%", "<<", ">>" and "&^"
+// require two ints and output an int.
 
 // isNumberExpr return true if p is a number expression. It looks for the
-// first digit that must be in the range '0' to '9'.
+// first digit that must be in the range '0' to '9', or for a call of a
+// built-in function.
 func isNumberExpr(p []byte) bool {
-	for i := 0; i < len(p); i++ {
-		if p[i] == '+' || p[i] == '-' || p[i] == ' ' || p[i] == '\t' || p[i] == '(' {
-			continue
-		}
-		return isIntDigit(p[i]) || (p[i] == '.' && i+1 < len(p) && isIntDigit(p[i+1]))
+	i := exprStart(p)
+	if i == len(p) {
+		return false
+	}
+	return isIntDigit(p[i]) || (p[i] == '.' && i+1 < len(p) && isIntDigit(p[i+1])) || isFunctionCall(p[i:])
+}
+
+// exprStart returns the index of the first byte of p that is not a sign,
+// a space or an open parenthesis.
+func exprStart(p []byte) int {
+	var i int
+	for i < len(p) && (p[i] == '+' || p[i] == '-' || p[i] == ' ' || p[i] == '\t' || p[i] == '(') {
+		i++
 	}
-	return false
+	return i
 }
 
 // numToken is a token produced by the number expression tokenizer.
@@ -56,7 +68,7 @@ func (t numToken) String() string {
 	case nil:
 	case error:
 		buf.WriteString(fmt.Sprintf(", val: %v", errStr(x)))
-	case int, float64:
+	case int, uint64, float64, *big.Rat, string:
 		buf.WriteString(fmt.Sprintf(", val: %v", x))
 	default:
 		buf.WriteString(fmt.Sprintf(", val: %v of type %s", x, reflect.TypeOf(x)))
@@ -72,6 +84,14 @@ type numTokenizer struct {
 	err    error    // the last error or nil if none
 	errPos int      // the index of the error
 	tk     numToken // the last token
+
+	disabled  Extension // the disabled extensions
+	exact     bool      // decimal numbers are exact *big.Rat values
+	functions map[string]func(args ...float64) (float64, error)
+	constants map[string]float64
+	overflow  OverflowPolicy          // handling of the integer overflows
+	warn      func(msg string, i int) // reports a warning at index i, when not nil
+	duration  bool                    // parsing the operand following a duration unit
 }
 
 func (tk *numTokenizer) init(input []byte) {
@@ -127,7 +147,7 @@ func (tk *numTokenizer) nextToken() {
 		return
 	}
 
-	if !tk.nextISODateTimeValue() && !tk.nextOperator() && !tk.nextBinValue() && !tk.nextHexValue() &&
+	if !tk.nextISODateTimeValue() && !tk.nextIdentifier() && !tk.nextOperator() && !tk.nextBinValue() && !tk.nextHexValue() &&
 		!tk.nextDecValue() && !tk.nextOctValue() && !tk.nextIntValue() {
 		tk.setError(ErrInvalidNumericExpression)
 	}
@@ -137,8 +157,8 @@ var tkOpTable = [256]tokenTag{
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 00
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 10
 	0, 0, 0, 0, 0, tagModulo, tagAnd, 0, tagOpenParen, // 20
-	tagCloseParen, tagMultiplication, tagPlus, 0, tagMinus, 0, tagDivision, // 29
-	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 30
+	tagCloseParen, tagMultiplication, tagPlus, tagComma, tagMinus, 0, tagDivision, // 29
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, tagShiftLeft, 0, tagShiftRight, 0, // 30
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 40
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, tagXor, 0, // 50
 	0, 0, 0, 0, tagDays, 0, 0, 0, tagHours, 0, 0, 0, 0, tagMinutes, 0, 0, // 60
@@ -148,12 +168,62 @@ var tkOpTable = [256]tokenTag{
 // nextOperator returns true and pops the operator if tk.p start with
 // an operatore. Otherwise return false.
 func (tk *numTokenizer) nextOperator() bool {
-	x := tkOpTable[tk.p[0]]
-	if x == tagUnknown {
+	x, n := tkOpTable[tk.p[0]], 1
+	switch x {
+	case tagUnknown:
 		return false
+	case tagShiftLeft, tagShiftRight:
+		// << and >> are two identical bytes
+		if len(tk.p) < 2 || tk.p[1] != tk.p[0] {
+			return false
+		}
+		n = 2
+	case tagAnd:
+		if len(tk.p) > 1 && tk.p[1] == '^' {
+			x, n = tagAndNot, 2
+		}
+	case tagMultiplication, tagXor:
+		// ** and ^^ are the power operator
+		if len(tk.p) > 1 && tk.p[1] == tk.p[0] {
+			x, n = tagPower, 2
+		}
+	}
+	switch {
+	case x >= tagWeeks && x <= tagSeconds:
+		if tk.disabled&ExtDurations != 0 {
+			tk.setError(ErrDurationDisabled)
+			return true
+		}
+	case tk.disabled&ExtNumberExpressions != 0:
+		// only a sign in front of the number is accepted
+		if tk.tk.tag != tagUnknown || (x != tagPlus && x != tagMinus) {
+			tk.setError(ErrNumberExpressionDisabled)
+			return true
+		}
 	}
 	tk.setToken(x, nil)
-	tk.popBytes(1)
+	tk.popBytes(n)
+	return true
+}
+
+// nextIdentifier returns true and pops the identifier or the size suffix
+// if tk.p start with an identifier. Otherwise return false. A duration suffix alone or
+// followed by a digit, like the h of 1h30m, is not an identifier.
+func (tk *numTokenizer) nextIdentifier() bool {
+	n := identifierLen(tk.p)
+	if n == 0 || (tkOpTable[tk.p[0]] != tagUnknown && (n == 1 || isIntDigit(tk.p[1]))) {
+		return false
+	}
+	if tk.disabled&ExtNumberExpressions != 0 {
+		tk.setError(ErrNumberExpressionDisabled)
+		return true
+	}
+	if unit, ok := sizeUnits[string(tk.p[:n])]; ok {
+		tk.setToken(tagSize, unit)
+	} else {
+		tk.setToken(tagIdentifier, string(tk.p[:n]))
+	}
+	tk.popBytes(n)
 	return true
 }
 
@@ -253,8 +323,7 @@ func (tk *numTokenizer) nextBinValue() bool {
 	}
 	val := decodeBinLiteral(tk.p[:n])
 	if val < 0 {
-		tk.setError(ErrNumberOverflow)
-		return true
+		return tk.nextUintValue(n)
 	}
 	tk.setToken(tagIntegerVal, val)
 	tk.popBytes(n)
@@ -337,8 +406,7 @@ func (tk *numTokenizer) nextHexValue() bool {
 	}
 	val := decodeHexLiteral(tk.p[:n])
 	if val < 0 {
-		tk.setError(ErrNumberOverflow)
-		return true
+		return tk.nextUintValue(n)
 	}
 	tk.setToken(tagIntegerVal, val)
 	tk.popBytes(n)
@@ -430,6 +498,19 @@ func (tk *numTokenizer) nextOctValue() bool {
 	}
 	val := decodeOctLiteral(tk.p[:n])
 	if val < 0 {
+		return tk.nextUintValue(n)
+	}
+	tk.setToken(tagIntegerVal, val)
+	tk.popBytes(n)
+	return true
+}
+
+// nextUintValue sets the integer token of the n bytes literal whose value
+// is above the maximum int as an uint64, or the overflow error when it is
+// above the maximum uint64. It returns true.
+func (tk *numTokenizer) nextUintValue(n int) bool {
+	val, err := strconv.ParseUint(string(tk.p[:n]), 0, 64)
+	if err != nil {
 		tk.setError(ErrNumberOverflow)
 		return true
 	}
@@ -486,10 +567,11 @@ func decodeIntLiteral(v []byte) int {
 		if v[p] == '_' {
 			continue
 		}
-		if val > 0x1999999999999999 {
+		d := uint64(v[p] - '0')
+		if val > (math.MaxUint64-d)/10 {
 			return -1
 		}
-		val = val*10 + uint64(v[p]-'0')
+		val = val*10 + d
 	}
 	if val&0x80000000_00000000 != 0 {
 		return -1
@@ -508,8 +590,7 @@ func (tk *numTokenizer) nextIntValue() bool {
 	}
 	val := decodeIntLiteral(tk.p[:n])
 	if val < 0 {
-		tk.setError(ErrNumberOverflow)
-		return true
+		return tk.nextUintValue(n)
 	}
 	tk.setToken(tagIntegerVal, val)
 	tk.popBytes(n)
@@ -627,6 +708,12 @@ func (tk *numTokenizer) nextDecValue() bool {
 		tk.setError(ErrInvalidDecimalNumber)
 		return true
 	}
+	if tk.exact {
+		r, _ := new(big.Rat).SetString(string(tk.p[:n]))
+		tk.setToken(tagDecimalVal, r)
+		tk.popBytes(n)
+		return true
+	}
 	tk.setToken(tagDecimalVal, val)
 	tk.popBytes(n)
 	return true
@@ -746,6 +833,10 @@ func (tk *numTokenizer) nextISODateTimeValue() bool {
 	if n == 0 {
 		return false
 	}
+	if tk.disabled&ExtISODateTimes != 0 {
+		tk.setError(ErrISODateTimeDisabled)
+		return true
+	}
 	if n < 0 {
 		tk.setError(ErrInvalidISODateTime)
 		return true
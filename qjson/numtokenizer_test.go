@@ -16,6 +16,10 @@ func TestIsNumberExpr(t *testing.T) {
 		{in: "a"},
 		{in: " "},
 		{in: "-( (0", out: true},
+		{in: "max(1, 2)", out: true},
+		{in: "(abs(", out: true},
+		{in: "max (1, 2)"},
+		{in: "foo(1)"},
 	}
 	for i, test := range tests {
 		if exp, out := test.out, isNumberExpr([]byte(test.in)); exp != out {
@@ -291,7 +295,7 @@ func TestNextBinValue(t *testing.T) {
 		{in: "0b0", out: true, pos: 3, tk: numToken{tag: tagIntegerVal, val: 0}},
 		{in: "0B1", out: true, pos: 3, tk: numToken{tag: tagIntegerVal, val: 1}},
 		{in: "0b_0", out: true, pos: 4, tk: numToken{tag: tagIntegerVal, val: 0}},
-		{in: "0b11111111_11111111_11111111_11111111_11111111_11111111_11111111_11111111", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrNumberOverflow}},
+		{in: "0b11111111_11111111_11111111_11111111_11111111_11111111_11111111_11111111", out: true, pos: 73, tk: numToken{tag: tagIntegerVal, val: uint64(0xFFFFFFFF_FFFFFFFF)}},
 		// 5
 		{in: "0b0000000000000001111111_11111111_11111111_11111111_11111111_11111111_11111111_11111111", out: true, pos: 87, tk: numToken{tag: tagIntegerVal, val: 9223372036854775807}},
 		{in: "0b11111111_", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrInvalidBinaryNumber}},
@@ -393,7 +397,7 @@ func TestNextHexValue(t *testing.T) {
 		{in: "0x_0", out: true, pos: 4, tk: numToken{tag: tagIntegerVal, val: 0}},
 		{in: "0x_0_A_B", out: true, pos: 8, tk: numToken{tag: tagIntegerVal, val: 0xAB}},
 		// 5
-		{in: "0xFFFFFFFF_FFFFFFFF", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrNumberOverflow}},
+		{in: "0xFFFFFFFF_FFFFFFFF", out: true, pos: 19, tk: numToken{tag: tagIntegerVal, val: uint64(0xFFFFFFFF_FFFFFFFF)}},
 		{in: "0x7FFFFFFF_FFFFFFFF", out: true, pos: 19, tk: numToken{tag: tagIntegerVal, val: 0x7FFFFFFF_FFFFFFFF}},
 		{in: "0x00000000_7FFFFFFF_FFFFFFFF", out: true, pos: 28, tk: numToken{tag: tagIntegerVal, val: 0x7FFFFFFF_FFFFFFFF}},
 		{in: "0x0af_", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrInvalidHexadecimalNumber}},
@@ -503,7 +507,7 @@ func TestNextOctValue(t *testing.T) {
 		{in: []byte("0o_0"), out: true, pos: 4, tk: numToken{tag: tagIntegerVal, val: 0}},
 		{in: []byte("0_0_2_3"), out: true, pos: 7, tk: numToken{tag: tagIntegerVal, val: 19}},
 		// 5
-		{in: []byte("01777777777777777777777"), out: true, pos: 0, tk: numToken{tag: tagError, val: ErrNumberOverflow}},
+		{in: []byte("01777777777777777777777"), out: true, pos: 23, tk: numToken{tag: tagIntegerVal, val: uint64(0xFFFFFFFF_FFFFFFFF)}},
 		{in: []byte("0777777777777777777777"), out: true, pos: 22, tk: numToken{tag: tagIntegerVal, val: 0x7FFFFFFF_FFFFFFFF}},
 		{in: []byte("00000000000777777777777777777777"), out: true, pos: 32, tk: numToken{tag: tagIntegerVal, val: 0x7FFFFFFF_FFFFFFFF}},
 		{in: []byte("0o750_"), out: true, tk: numToken{tag: tagError, val: ErrInvalidOctalNumber}},
@@ -574,6 +578,8 @@ func TestDecodeIntLiteral(t *testing.T) {
 		{in: "1_0_0", out: 100},
 		{in: "18446744073709551615", out: -1}, // 0xFFFFFFFF_FFFFFFFF
 		{in: "9223372036854775807", out: 0x7FFFFFFFFFFFFFFF},
+		{in: "18446744073709551616", out: -1}, // 2^64
+		{in: "18446744073709551617", out: -1},
 	}
 	for i, test := range tests {
 		if out := decodeIntLiteral([]byte(test.in)); out != test.out {
@@ -593,12 +599,15 @@ func TestNextIntValue(t *testing.T) {
 		{in: "0", out: true, pos: 1, tk: numToken{tag: tagIntegerVal, val: 0}},
 		{in: "10", out: true, pos: 2, tk: numToken{tag: tagIntegerVal, val: 10}},
 		{in: "1_0_0", out: true, pos: 5, tk: numToken{tag: tagIntegerVal, val: 100}},
-		{in: "18446744073709551615", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrNumberOverflow}},
+		{in: "18446744073709551615", out: true, pos: 20, tk: numToken{tag: tagIntegerVal, val: uint64(0xFFFFFFFF_FFFFFFFF)}},
 		// 5
 		{in: "9223372036854775807", out: true, pos: 19, tk: numToken{tag: tagIntegerVal, val: 0x7FFFFFFF_FFFFFFFF}},
 		{in: "750_", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrInvalidIntegerNumber}},
 		{in: "00", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrInvalidIntegerNumber}},
 		{in: "184467440737095516150", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrNumberOverflow}},
+		{in: "18446744073709551616", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrNumberOverflow}},
+		// 10
+		{in: "18446744073709551617", out: true, pos: 0, tk: numToken{tag: tagError, val: ErrNumberOverflow}},
 	}
 	for i, test := range tests {
 		var hasErrors bool
@@ -841,7 +850,9 @@ func TestNumTokenizer(t *testing.T) {
 		{in: "", out: []numToken{{tag: tagError, val: ErrEndOfInput}}},
 		{in: "( 10", out: []numToken{{tag: tagOpenParen}, {tag: tagIntegerVal, pos: 2, val: 10}, {tag: tagError, val: ErrEndOfInput, pos: 4}}},
 		{in: "-0x1A", out: []numToken{{tag: tagMinus}, {tag: tagIntegerVal, pos: 1, val: 26}, {tag: tagError, val: ErrEndOfInput, pos: 5}}},
-		{in: "2.3 x", out: []numToken{{tag: tagDecimalVal, val: 2.3}, {tag: tagError, pos: 4, val: ErrInvalidNumericExpression}}},
+		{in: "2.3 $", out: []numToken{{tag: tagDecimalVal, val: 2.3}, {tag: tagError, pos: 4, val: ErrInvalidNumericExpression}}},
+		{in: "2.3 x", out: []numToken{{tag: tagDecimalVal, val: 2.3}, {tag: tagIdentifier, pos: 4, val: "x"}, {tag: tagError, pos: 5, val: ErrEndOfInput}}},
+		{in: "2 ** x_1", out: []numToken{{tag: tagIntegerVal, val: 2}, {tag: tagPower, pos: 2}, {tag: tagIdentifier, pos: 5, val: "x_1"}, {tag: tagError, pos: 8, val: ErrEndOfInput}}},
 		{in: "0b10 23_", out: []numToken{{tag: tagIntegerVal, val: 2}, {tag: tagError, pos: 5, val: ErrInvalidIntegerNumber}}},
 		// 5
 		{in: "0o750 ", out: []numToken{{tag: tagIntegerVal, val: 488}, {tag: tagError, val: ErrEndOfInput, pos: 6}}},
@@ -0,0 +1,210 @@
+package qjson
+
+// DefaultMaxDepth is the default maximum nesting depth of objects and
+// arrays.
+const DefaultMaxDepth = 200
+
+// DecodeOptions are the options of the decoding of a QJSON text. The zero
+// value selects the default decoding.
+type DecodeOptions struct {
+	// MaxDepth is the maximum nesting depth of objects and arrays. It is
+	// DefaultMaxDepth when 0.
+	MaxDepth int
+
+	// Disabled is the set of disabled QJSON extensions. Their use in the
+	// QJSON text is a syntax error.
+	Disabled Extension
+
+	// Literals maps the quoteless values decoded as a literal to the
+	// literal "true", "false" or "null". Entries with another literal are
+	// ignored. The other quoteless values are numbers or strings. When
+	// nil, the literals are true, false, null, yes, no, on and off in
+	// lower, title and upper case.
+	Literals map[string]string
+
+	// Warn, when not nil, is called with each warning of the decoding.
+	Warn func(Warning)
+
+	// KeepNumberLiterals writes the number values that are valid JSON
+	// numbers as written, instead of their value. Number expressions and
+	// the other number literals are converted as usual.
+	KeepNumberLiterals bool
+
+	// DecimalPlaces, when positive, selects the evaluation of the number
+	// expressions with exact decimal numbers. The result is written
+	// exactly and must have at most DecimalPlaces fractional digits,
+	// otherwise ErrInexactDecimal is returned. The division of integers
+	// is exact too, so that 7 / 2 is 3.5.
+	DecimalPlaces int
+
+	// Functions are the functions that may be called in the number
+	// expressions, in addition to the built-in functions they override.
+	// The error returned by a function is reported at its name.
+	Functions map[string]func(args ...float64) (float64, error)
+
+	// Constants are the values of the identifiers in the number
+	// expressions, like NUM_CPU. A quoteless value starting with a
+	// constant or a call of a function is a number expression. The
+	// identifiers start with a letter or an underscore, followed by
+	// letters, digits or underscores.
+	Constants map[string]float64
+
+	// IntegerOverflow selects the handling of the integer results of the
+	// number expressions out of the int64 and uint64 ranges.
+	IntegerOverflow OverflowPolicy
+
+	// Root selects the kind of the top level value.
+	Root RootKind
+
+	// Duplicates selects the decoding of the members of an object with
+	// the same name. The names are compared after unquoting. Parse keeps
+	// all the members in the document tree, but reports the duplicates
+	// with DuplicateError.
+	Duplicates DuplicatePolicy
+}
+
+// A RootKind selects the kind of the top level value of a QJSON text.
+type RootKind byte
+
+const (
+	// RootDefault is the implicit object whose members are the top level
+	// members, or an array when the text starts with [.
+	RootDefault RootKind = iota
+	// RootObject is only the implicit object.
+	RootObject
+	// RootValue is a single value of any kind, like the top level value of
+	// a JSON text.
+	RootValue
+)
+
+// An OverflowPolicy selects the handling of the integer overflows in the
+// number expressions.
+type OverflowPolicy byte
+
+const (
+	// OverflowError returns ErrNumberOverflow at the operator. This is
+	// the default.
+	OverflowError OverflowPolicy = iota
+	// OverflowFloat converts the result to a float64 and reports a
+	// warning to DecodeOptions.Warn.
+	OverflowFloat
+)
+
+// A DuplicatePolicy selects the decoding of the members of an object with
+// the same name.
+type DuplicatePolicy byte
+
+const (
+	// DuplicateKeep keeps all the members. This is the default.
+	DuplicateKeep DuplicatePolicy = iota
+	// DuplicateError returns a DuplicateKeyError.
+	DuplicateError
+	// DuplicateFirstWins keeps the first member.
+	DuplicateFirstWins
+	// DuplicateLastWins keeps the value of the last member at the place of
+	// the first member.
+	DuplicateLastWins
+	// DuplicateMerge merges the members of object values recursively. The
+	// last value wins when one of the values is not an object.
+	DuplicateMerge
+)
+
+// BasicLiterals returns a literal table with only true, false and null
+// in lower, title and upper case. With this table, yes, no, on and off
+// are decoded as strings.
+func BasicLiterals() map[string]string {
+	return map[string]string{
+		"true": "true", "True": "true", "TRUE": "true",
+		"false": "false", "False": "false", "FALSE": "false",
+		"null": "null", "Null": "null", "NULL": "null",
+	}
+}
+
+// literal returns the literal of the quoteless value p, or the empty
+// string if p is not a literal.
+func (o *DecodeOptions) literal(p []byte) string {
+	if o.Literals == nil {
+		return isLiteralValue(p)
+	}
+	switch lit := o.Literals[string(p)]; lit {
+	case "true", "false", "null":
+		return lit
+	}
+	return ""
+}
+
+// basicLiterals are the standard spellings of true, false and null.
+var basicLiterals = BasicLiterals()
+
+// isAlternateLiteral returns true if the quoteless value p decoded as the
+// literal lit is not one of its standard spellings, like yes or a custom
+// entry of the Literals table.
+func isAlternateLiteral(p []byte, lit string) bool {
+	return basicLiterals[string(p)] != lit
+}
+
+// A Warning reports a conversion made by the decoding that may not be
+// intended, like a quoteless no decoded as false.
+type Warning struct {
+	Msg  string // the warning message
+	Text string // the text of the QJSON input the warning is about
+	Pos         // position of the text in the QJSON input
+}
+
+func (w Warning) String() string {
+	return w.Msg + " at " + w.Pos.String()
+}
+
+// isNumberExpr returns true if p is a number expression, which may start
+// with a constant or a function call of the options.
+func (o *DecodeOptions) isNumberExpr(p []byte) bool {
+	if isNumberExpr(p) {
+		return true
+	}
+	if len(o.Constants) == 0 && len(o.Functions) == 0 {
+		return false
+	}
+	p = p[exprStart(p):]
+	n := identifierLen(p)
+	if _, ok := o.Constants[string(p[:n])]; ok && n != 0 {
+		return true
+	}
+	_, ok := o.Functions[string(p[:n])]
+	return ok && n != 0 && n < len(p) && p[n] == '('
+}
+
+// maxDepth returns the maximum nesting depth of objects and arrays.
+func (o *DecodeOptions) maxDepth() int {
+	if o.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return o.MaxDepth
+}
+
+// An Extension is a set of QJSON extensions to JSON.
+type Extension uint
+
+const (
+	// ExtAlternateLiterals are the literals other than the standard
+	// spellings of true, false and null, like yes, no, on and off of the
+	// default literal table or the custom entries of the Literals table.
+	ExtAlternateLiterals Extension = 1 << iota
+	// ExtNumberExpressions are the numeric expressions. A number with an
+	// optional sign is still accepted when disabled.
+	ExtNumberExpressions
+	// ExtDurations are the duration numbers like 1h30m.
+	ExtDurations
+	// ExtISODateTimes are the ISO date times converted to seconds.
+	ExtISODateTimes
+	// ExtQuotelessStrings are the quoteless string values. Quoteless
+	// member names are still accepted when disabled.
+	ExtQuotelessStrings
+	// ExtSingleQuotes are the single quoted strings.
+	ExtSingleQuotes
+	// ExtOptionalCommas are the missing commas between members and values.
+	ExtOptionalCommas
+
+	// AllExtensions are all the extensions above.
+	AllExtensions = ExtAlternateLiterals | ExtNumberExpressions | ExtDurations | ExtISODateTimes |
+		ExtQuotelessStrings | ExtSingleQuotes | ExtOptionalCommas
+)
@@ -0,0 +1,290 @@
+package qjson
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDecodeOptionsMaxDepth(t *testing.T) {
+	in := "a: [[{b: []}]]"
+	exp := "too many object or array encapsulations at line 1 col 11"
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := DecodeWithOptions([]byte(in), DecodeOptions{MaxDepth: 3}); e2s(err) != exp {
+				t.Errorf("expected error %q, got %q", exp, e2s(err))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := DecodeWithOptions([]byte(in), DecodeOptions{}); err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	opts := DecodeOptions{MaxDepth: 3}
+	var v interface{}
+	if err := UnmarshalWithOptions([]byte(in), &v, opts); e2s(err) != exp {
+		t.Fatalf("expected error %q, got %q", exp, e2s(err))
+	}
+	d := NewDecoder(strings.NewReader(in))
+	d.SetOptions(opts)
+	if err := d.WriteJSONTo(&bytes.Buffer{}); e2s(err) != exp {
+		t.Fatalf("expected error %q, got %q", exp, e2s(err))
+	}
+	if _, err := Parse([]byte(in), WithDecodeOptions(opts)); e2s(err) != exp {
+		t.Fatalf("expected error %q, got %q", exp, e2s(err))
+	}
+	if _, err := Parse([]byte(in), WithDecodeOptions(DecodeOptions{MaxDepth: 4})); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDecodeOptionsDisabled(t *testing.T) {
+	tests := []struct {
+		in       string
+		disabled Extension
+		literals map[string]string
+		out      string
+		err      string
+	}{
+		{in: "a: yes", disabled: ExtAlternateLiterals, err: "alternate literals are disabled at line 1 col 4"},
+		{in: "a: True, b: NULL", disabled: ExtAlternateLiterals, out: `{"a":true,"b":null}`},
+		{in: "a: yes", disabled: AllExtensions &^ ExtAlternateLiterals, out: `{"a":true}`},
+		{in: "a: enabled", disabled: ExtAlternateLiterals, literals: map[string]string{"enabled": "true"},
+			err: "alternate literals are disabled at line 1 col 4"},
+		{in: "a: true, b: NULL", disabled: ExtAlternateLiterals, literals: BasicLiterals(), out: `{"a":true,"b":null}`},
+		{in: "a: enabled", literals: map[string]string{"enabled": "true"}, out: `{"a":true}`},
+		{in: "a: 1+2", disabled: ExtNumberExpressions, err: "numeric expressions are disabled at line 1 col 5"},
+		{in: "a: (1)", disabled: ExtNumberExpressions, err: "numeric expressions are disabled at line 1 col 4"},
+		{in: "a: --1", disabled: ExtNumberExpressions, err: "numeric expressions are disabled at line 1 col 5"},
+		{in: "a: -1.5, b: +0x10, c: 1h30m", disabled: ExtNumberExpressions, out: `{"a":-1.5,"b":16,"c":5400}`},
+		{in: "a: max(1, 2)", disabled: ExtNumberExpressions, err: "numeric expressions are disabled at line 1 col 4"},
+		{in: "a: 1h30m", disabled: ExtDurations, err: "durations are disabled at line 1 col 5"},
+		{in: "a: 2+3", disabled: ExtDurations, out: `{"a":5}`},
+		{in: "a: 2021-01-01T00:00:00Z", disabled: ExtISODateTimes, err: "ISO date times are disabled at line 1 col 4"},
+		{in: "a: b", disabled: ExtQuotelessStrings, err: "quoteless strings are disabled at line 1 col 4"},
+		{in: `a: "b", c: true, d: 1`, disabled: ExtQuotelessStrings, out: `{"a":"b","c":true,"d":1}`},
+		{in: "a: 'b'", disabled: ExtSingleQuotes, err: "single quoted strings are disabled at line 1 col 4"},
+		{in: "'a': 1", disabled: ExtSingleQuotes, err: "single quoted strings are disabled at line 1 col 1"},
+		{in: "a: [[1] 2]", disabled: ExtOptionalCommas, err: "missing comma at line 1 col 9"},
+		{in: "a: 1\nb: 2", disabled: ExtOptionalCommas, err: "missing comma at line 2 col 1"},
+		{in: "a: [1, 2], b: 3", disabled: ExtOptionalCommas, out: `{"a":[1,2],"b":3}`},
+		{in: `a: ["b", 1, -2.5, {c: false}]`, disabled: AllExtensions, out: `{"a":["b",1,-2.5,{"c":false}]}`},
+	}
+	for _, test := range tests {
+		out, err := DecodeWithOptions([]byte(test.in), DecodeOptions{Disabled: test.disabled, Literals: test.literals})
+		if e2s(err) != test.err {
+			t.Errorf("input %q: expected error %q, got %q", test.in, test.err, e2s(err))
+		} else if err == nil && string(out) != test.out {
+			t.Errorf("input %q: expected output %q, got %q", test.in, test.out, out)
+		}
+	}
+}
+
+func TestDecodeOptionsLiterals(t *testing.T) {
+	tests := []struct {
+		in       string
+		literals map[string]string
+		out      string
+		warnings []string
+	}{
+		{in: "a: no, b: On, c: true", out: `{"a":false,"b":true,"c":true}`,
+			warnings: []string{"quoteless no decoded as false at line 1 col 4", "quoteless On decoded as true at line 1 col 11"}},
+		{in: "a: [NO, SE]\nb: TRUE", out: `{"a":[false,"SE"],"b":true}`,
+			warnings: []string{"quoteless NO decoded as false at line 1 col 5"}},
+		{in: "a: True, b: FALSE, c: Null", out: `{"a":true,"b":false,"c":null}`},
+		{in: "a: [NO, SE], b: on, c: Null", literals: BasicLiterals(), out: `{"a":["NO","SE"],"b":"on","c":null}`},
+		{in: "a: True, b: Yes", literals: map[string]string{"True": "false", "Yes": "true"}, out: `{"a":false,"b":true}`,
+			warnings: []string{"quoteless True decoded as false at line 1 col 4", "quoteless Yes decoded as true at line 1 col 13"}},
+		{in: "a: oui, b: non, c: true, d: nil", literals: map[string]string{"oui": "true", "non": "false", "nil": "nothing"},
+			out:      `{"a":true,"b":false,"c":"true","d":"nil"}`,
+			warnings: []string{"quoteless oui decoded as true at line 1 col 4", "quoteless non decoded as false at line 1 col 12"}},
+	}
+	for _, test := range tests {
+		var warnings []string
+		opts := DecodeOptions{Literals: test.literals, Warn: func(w Warning) { warnings = append(warnings, w.String()) }}
+		out, err := DecodeWithOptions([]byte(test.in), opts)
+		if err != nil {
+			t.Errorf("input %q: unexpected error %v", test.in, err)
+			continue
+		}
+		if string(out) != test.out {
+			t.Errorf("input %q: expected output %q, got %q", test.in, test.out, out)
+		}
+		if strings.Join(warnings, "\n") != strings.Join(test.warnings, "\n") {
+			t.Errorf("input %q: expected warnings %q, got %q", test.in, test.warnings, warnings)
+		}
+	}
+}
+
+func TestDecodeOptionsRoot(t *testing.T) {
+	tests := []struct {
+		in   string
+		root RootKind
+		out  string
+		err  string
+	}{
+		// 0
+		{in: "a: 1", out: `{"a":1}`},
+		{in: "// hosts\n[\n  alpha\n  beta\n]\n", out: `["alpha","beta"]`},
+		{in: "[1, 2]", root: RootObject, err: "expect string identifier at line 1 col 1"},
+		{in: "[1] 2", err: "unexpected token after the root value at line 1 col 5"},
+		{in: "{a: 1}", err: "expect string identifier at line 1 col 1"},
+		// 5
+		{in: "{a: 1}", root: RootValue, out: `{"a":1}`},
+		{in: "1h + 1", root: RootValue, out: `3601`},
+		{in: "hello world", root: RootValue, out: `"hello world"`},
+		{in: "a: 1", root: RootValue, err: "unexpected token after the root value at line 1 col 2"},
+		{in: "/* empty */", root: RootValue, err: "unexpected end of input at line 1 col 12"},
+	}
+	for i, test := range tests {
+		out, err := DecodeWithOptions([]byte(test.in), DecodeOptions{Root: test.root})
+		if e2s(err) != test.err {
+			t.Errorf("%d expected error %q, got %q", i, test.err, e2s(err))
+		} else if err == nil && string(out) != test.out {
+			t.Errorf("%d expected output %q, got %q", i, test.out, out)
+		}
+	}
+	var hosts []string
+	if err := Unmarshal([]byte("[alpha, beta]"), &hosts); err != nil || len(hosts) != 2 || hosts[1] != "beta" {
+		t.Fatalf("unexpected hosts %q, error %v", hosts, err)
+	}
+	doc, err := Parse([]byte("{a: 1}"), WithDecodeOptions(DecodeOptions{Root: RootValue}))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if o, ok := doc.Root.(*Object); !ok || o.Pos.Column != 1 || o.End.Column != 7 || len(o.Members) != 1 {
+		t.Fatalf("unexpected root %+v", doc.Root)
+	}
+}
+
+func TestDecodeOptionsKeepNumberLiterals(t *testing.T) {
+	in := "a: 0.10, b: 1.5E+3, c: -0, d: 1_000, e: .5, f: 2 * 0.1, g: 0x10, h: 1."
+	exp := `{"a":0.10,"b":1.5E+3,"c":-0,"d":1000,"e":0.5,"f":0.2,"g":16,"h":1}`
+	out, err := DecodeWithOptions([]byte(in), DecodeOptions{KeepNumberLiterals: true})
+	if err != nil || string(out) != exp {
+		t.Fatalf("expected %s, got %s, %v", exp, out, err)
+	}
+	exp = `{"a":0.1,"b":1500,"c":0,"d":1000,"e":0.5,"f":0.2,"g":16,"h":1}`
+	if out, err = Decode([]byte(in)); err != nil || string(out) != exp {
+		t.Fatalf("expected %s, got %s, %v", exp, out, err)
+	}
+}
+
+func TestDecodeOptionsDecimalPlaces(t *testing.T) {
+	tests := []struct {
+		in, out string
+		err     error
+	}{
+		{in: "a: 0.1 + 0.2", out: `{"a":0.3}`},
+		{in: "a: 19.99 * 3", out: `{"a":59.97}`},
+		{in: "a: 1.10 * 2, b: 7 / 2, c: -2.5 + 1", out: `{"a":2.2,"b":3.5,"c":-1.5}`},
+		{in: "a: 10 / 4, b: 10 / 5, c: 7 / 2 * 2 + 1", out: `{"a":2.5,"b":2,"c":8}`},
+		{in: "a: 1 / 3", err: ErrInexactDecimal},
+		{in: "a: 1e20 + 0.01", out: `{"a":100000000000000000000.01}`},
+		{in: "a: 0.5 * 10", out: `{"a":5}`},
+		{in: "a: 1e30TB, b: 1.5KiB, c: 0.0001KB", err: ErrFractionalSize},
+		{in: "a: 1e30TB, b: 1.5KiB", out: `{"a":1000000000000000000000000000000000000000000,"b":1536}`},
+		{in: "a: floor(1e30), b: round(1e30 + 0.5), c: ceil(-1e30 - 0.5)",
+			out: `{"a":1000000000000000000000000000000,"b":1000000000000000000000000000001,"c":-1000000000000000000000000000000}`},
+		{in: "a: 1.0 / 3", err: ErrInexactDecimal},
+		{in: "a: 0.001 * 0.5", err: ErrInexactDecimal},
+		{in: "a: 1.5 / 0", err: ErrDivisionByZero},
+		{in: "a: 1.5 % 1", err: ErrOperandsMustBeInteger},
+	}
+	for i, test := range tests {
+		out, err := DecodeWithOptions([]byte(test.in), DecodeOptions{DecimalPlaces: 3})
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+		} else if err == nil && string(out) != test.out {
+			t.Errorf("test %d: expected %s, got %s", i, test.out, out)
+		}
+	}
+	out, err := Decode([]byte("a: 0.1 + 0.2"))
+	if exp := `{"a":0.30000000000000004}`; err != nil || string(out) != exp {
+		t.Errorf("expected %s, got %s, %v", exp, out, err)
+	}
+}
+
+func TestDecodeOptionsFunctionsAndConstants(t *testing.T) {
+	errNegative := errors.New("negative size")
+	opts := DecodeOptions{
+		Constants: map[string]float64{"NUM_CPU": 8, "PAGE_SIZE": 4096, "RATIO": 0.75},
+		Functions: map[string]func(args ...float64) (float64, error){
+			"clamp": func(args ...float64) (float64, error) {
+				return math.Max(args[1], math.Min(args[0], args[2])), nil
+			},
+			"pages": func(args ...float64) (float64, error) {
+				if args[0] < 0 {
+					return 0, errNegative
+				}
+				return math.Ceil(args[0] / 4096), nil
+			},
+		},
+	}
+	tests := []struct {
+		in, out string
+		err     error
+	}{
+		{in: "a: NUM_CPU * 2, b: PAGE_SIZE << 1, c: RATIO", out: `{"a":16,"b":8192,"c":0.75}`},
+		{in: "a: clamp(2 * NUM_CPU, 1, 10), b: [pages(10000), max(1, NUM_CPU)]", out: `{"a":10,"b":[3,8]}`},
+		{in: "a: -NUM_CPU % 3", out: `{"a":-2}`},
+		{in: "a: NUM_CPUS, b: clamps(1)", out: `{"a":"NUM_CPUS","b":"clamps(1)"}`},
+		{in: "a: NUM_CPU * CORES", err: ErrUnknownConstant},
+		{in: "a: pages(-1)", err: errNegative},
+	}
+	for i, test := range tests {
+		out, err := DecodeWithOptions([]byte(test.in), opts)
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: expected error %v, got %v", i, test.err, err)
+		} else if err == nil && string(out) != test.out {
+			t.Errorf("test %d: expected %s, got %s", i, test.out, out)
+		}
+	}
+	exp := "negative size at line 1 col 4"
+	if _, err := DecodeWithOptions([]byte("a: pages(-1)"), opts); e2s(err) != exp {
+		t.Errorf("expected error %q, got %q", exp, e2s(err))
+	}
+}
+
+func TestDecodeOptionsIntegerOverflow(t *testing.T) {
+	in := "a: 1,\nb: 0xFFFF_FFFF_FFFF_FFFF * 2"
+	exp := "number overflow at line 2 col 26"
+	if _, err := Decode([]byte(in)); e2s(err) != exp {
+		t.Fatalf("expected error %q, got %q", exp, e2s(err))
+	}
+	var warnings []string
+	opts := DecodeOptions{
+		IntegerOverflow: OverflowFloat,
+		Warn:            func(w Warning) { warnings = append(warnings, w.String()) },
+	}
+	out, err := DecodeWithOptions([]byte(in), opts)
+	if exp := `{"a":1,"b":36893488147419103000}`; err != nil || string(out) != exp {
+		t.Fatalf("expected %s, got %s, %v", exp, out, err)
+	}
+	if exp := "integer overflow converted to float at line 2 col 26"; len(warnings) != 1 || warnings[0] != exp {
+		t.Fatalf("expected warning %q, got %q", exp, warnings)
+	}
+	exp = "number overflow at line 1 col 8"
+	for _, in := range []string{"a: 1 + 18446744073709551616", "a: 1 + 18446744073709551617"} {
+		if _, err := Decode([]byte(in)); e2s(err) != exp {
+			t.Fatalf("%s: expected error %q, got %q", in, exp, e2s(err))
+		}
+	}
+	out, err = DecodeWithOptions([]byte("a: 1 << 200, b: 3 << 1000"), opts)
+	if exp := `{"a":1.6069380442589903e+60,"b":3.214525821558802e+301}`; err != nil || string(out) != exp {
+		t.Fatalf("expected %s, got %s, %v", exp, out, err)
+	}
+	if _, err := DecodeWithOptions([]byte("a: 1 << 0xFFFF_FFFF_FFFF_FFFF"), opts); !errors.Is(err, ErrNumberOverflow) {
+		t.Fatalf("expected ErrNumberOverflow, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package qjson
+
+import "strconv"
+
+// TokenKind is the kind of a token returned by a Scanner.
+type TokenKind byte
+
+// The kinds of tokens. Numbers, number expressions and the literals
+// true, false, null and their alternate forms are quoteless strings.
+const (
+	TokenError TokenKind = iota
+	TokenEOF
+	TokenOpenBrace
+	TokenCloseBrace
+	TokenOpenSquare
+	TokenCloseSquare
+	TokenColon
+	TokenComma
+	TokenQuotelessString
+	TokenDoubleQuotedString
+	TokenSingleQuotedString
+	TokenMultilineString
+	TokenComment
+	TokenWhitespace
+	TokenNewline
+)
+
+var tokenKindStr = [...]string{
+	"TokenError",
+	"TokenEOF",
+	"TokenOpenBrace",
+	"TokenCloseBrace",
+	"TokenOpenSquare",
+	"TokenCloseSquare",
+	"TokenColon",
+	"TokenComma",
+	"TokenQuotelessString",
+	"TokenDoubleQuotedString",
+	"TokenSingleQuotedString",
+	"TokenMultilineString",
+	"TokenComment",
+	"TokenWhitespace",
+	"TokenNewline",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < len(tokenKindStr) {
+		return tokenKindStr[k]
+	}
+	return "TokenKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+var tagKind = map[tokenTag]TokenKind{
+	tagOpenBrace:          TokenOpenBrace,
+	tagCloseBrace:         TokenCloseBrace,
+	tagOpenSquare:         TokenOpenSquare,
+	tagCloseSquare:        TokenCloseSquare,
+	tagColon:              TokenColon,
+	tagComma:              TokenComma,
+	tagQuotelessString:    TokenQuotelessString,
+	tagDoubleQuotedString: TokenDoubleQuotedString,
+	tagSingleQuotedString: TokenSingleQuotedString,
+	tagMultilineString:    TokenMultilineString,
+}
+
+// A Token is a token of a QJSON text.
+type Token struct {
+	Kind TokenKind
+	Raw  []byte // the token as written in the QJSON text
+	Pos  Pos    // position of the first byte of the token or of the error
+	Err  error  // the error when Kind is TokenError
+}
+
+// ScanMode controls the tokens returned by a Scanner.
+type ScanMode uint
+
+// The scan modes. Comments, whitespaces and newlines are skipped by default.
+const (
+	ScanComments    ScanMode = 1 << iota // return comment tokens
+	ScanWhitespaces                      // return whitespace and newline tokens
+)
+
+// A Scanner splits a QJSON text into tokens with the lexing rules of the
+// decoder. When comments, whitespaces and newlines are returned, the
+// concatenation of the raw bytes of the tokens is the QJSON text.
+type Scanner struct {
+	tk   tokenizer
+	mode ScanMode
+}
+
+// NewScanner returns a scanner of the QJSON text in input.
+func NewScanner(input []byte, mode ScanMode) *Scanner {
+	s := &Scanner{mode: mode}
+	s.tk.init(input)
+	return s
+}
+
+// Scan returns the next token. It returns a TokenEOF token at the end of
+// input, and a TokenError token when an invalid token is met. Once the
+// end of input or an error is reached, Scan returns the same token.
+func (s *Scanner) Scan() Token {
+	tk := &s.tk
+	if tk.tk.tag == tagError {
+		return s.errorToken()
+	}
+	for len(tk.p) != 0 {
+		start := tk.pos
+		kind, mode := TokenWhitespace, ScanWhitespaces
+		var err *atError
+		if whitespace(tk.p) != 0 {
+			tk.skipWhitespaces()
+		} else if tk.popNewline() {
+			kind = TokenNewline
+		} else if isLineComment(tk.p) {
+			kind, mode = TokenComment, ScanComments
+			err = tk.skipLineText()
+		} else if ok, e := tk.skipMultilineComment(); ok || e != nil {
+			kind, mode, err = TokenComment, ScanComments, e
+		} else {
+			break
+		}
+		if err != nil {
+			tk.tk = token{tag: tagError, pos: err.pos, val: err.err}
+			return s.errorToken()
+		}
+		if s.mode&mode != 0 {
+			return Token{Kind: kind, Raw: tk.text(start.b, tk.b), Pos: tk.position(start)}
+		}
+	}
+	tk.scanToken()
+	if tk.tk.tag == tagError {
+		return s.errorToken()
+	}
+	end := tk.b
+	if tk.tk.tag == tagQuotelessString {
+		// give back the whitespaces following the quoteless string
+		end = tk.tk.b + len(tk.tk.val.([]byte))
+		tk.p = tk.in[end:]
+		tk.b = end
+	}
+	return Token{Kind: tagKind[tk.tk.tag], Raw: tk.text(tk.tk.b, end), Pos: tk.position(tk.tk.pos)}
+}
+
+// errorToken returns the error or end of input token.
+func (s *Scanner) errorToken() Token {
+	t := Token{Kind: TokenError, Pos: s.tk.position(s.tk.tk.pos), Err: s.tk.tk.val.(error)}
+	if t.Err == ErrEndOfInput {
+		t.Kind, t.Err = TokenEOF, nil
+	}
+	return t
+}
@@ -0,0 +1,56 @@
+package qjson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	tests := []struct {
+		in   string
+		mode ScanMode
+		out  string
+	}{
+		// 0
+		{in: "", out: "TokenEOF@1:1"},
+		{in: "a:b", out: "TokenQuotelessString \"a\"@1:1 TokenColon \":\"@1:2 TokenQuotelessString \"b\"@1:3 TokenEOF@1:4"},
+		{in: "a : 2 * 3 ,", out: "TokenQuotelessString \"a\"@1:1 TokenColon \":\"@1:3 TokenQuotelessString \"2 * 3\"@1:5 TokenComma \",\"@1:11 TokenEOF@1:12"},
+		{in: "a : 2 ,", mode: ScanWhitespaces, out: "TokenQuotelessString \"a\"@1:1 TokenWhitespace \" \"@1:2 TokenColon \":\"@1:3 TokenWhitespace \" \"@1:4 TokenQuotelessString \"2\"@1:5 TokenWhitespace \" \"@1:6 TokenComma \",\"@1:7 TokenEOF@1:8"},
+		{in: "# c\n[x] // d", mode: ScanComments, out: "TokenComment \"# c\"@1:1 TokenOpenSquare \"[\"@2:1 TokenQuotelessString \"x\"@2:2 TokenCloseSquare \"]\"@2:3 TokenComment \"// d\"@2:5 TokenEOF@2:9"},
+		// 5
+		{in: "{/* a\nb */'é'\r\n\"c\"}", mode: ScanComments | ScanWhitespaces, out: "TokenOpenBrace \"{\"@1:1 TokenComment \"/* a\\nb */\"@1:2 TokenSingleQuotedString \"'é'\"@2:5 TokenNewline \"\\r\\n\"@2:8 TokenDoubleQuotedString \"\\\"c\\\"\"@3:1 TokenCloseBrace \"}\"@3:4 TokenEOF@3:5"},
+		{in: "a:\n  `\\n\n  x\n  `", mode: ScanWhitespaces, out: "TokenQuotelessString \"a\"@1:1 TokenColon \":\"@1:2 TokenNewline \"\\n\"@1:3 TokenWhitespace \"  \"@2:1 TokenMultilineString \"`\\\\n\\n  x\\n  `\"@2:3 TokenEOF@4:4"},
+		{in: "a: 'b", out: "TokenQuotelessString \"a\"@1:1 TokenColon \":\"@1:2 TokenError unclosed single quote string@1:4"},
+		{in: "a /* b", mode: ScanComments, out: "TokenQuotelessString \"a\"@1:1 TokenError unclosed /*...*/ comment@1:3"},
+		{in: "t: 1970-01-01T00:01:02 ", mode: ScanWhitespaces, out: "TokenQuotelessString \"t\"@1:1 TokenColon \":\"@1:2 TokenWhitespace \" \"@1:3 TokenQuotelessString \"1970-01-01T00:01:02\"@1:4 TokenWhitespace \" \"@1:23 TokenEOF@1:24"},
+	}
+	for i, test := range tests {
+		var out []string
+		var raw strings.Builder
+		s := NewScanner([]byte(test.in), test.mode)
+		for {
+			tok := s.Scan()
+			str := tok.Kind.String()
+			if tok.Kind == TokenError {
+				str += " " + tok.Err.Error()
+			} else if tok.Kind != TokenEOF {
+				str += fmt.Sprintf(" %q", tok.Raw)
+			}
+			out = append(out, fmt.Sprintf("%s@%d:%d", str, tok.Pos.Line, tok.Pos.Column))
+			raw.Write(tok.Raw)
+			if tok.Kind == TokenEOF || tok.Kind == TokenError {
+				if again := s.Scan(); again.Kind != tok.Kind || again.Pos != tok.Pos {
+					t.Fatalf("%d expected same final token, got %v", i, again)
+				}
+				break
+			}
+		}
+		if res := strings.Join(out, " "); res != test.out {
+			t.Fatalf("%d expected\n%s\ngot\n%s", i, test.out, res)
+		}
+		if test.mode == ScanComments|ScanWhitespaces && raw.String() != test.in {
+			t.Fatalf("%d expected raw bytes %q, got %q", i, test.in, raw.String())
+		}
+	}
+}
@@ -2,6 +2,7 @@ package qjson
 
 import (
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 )
@@ -10,6 +11,7 @@ type pos struct {
 	b int // index of first byte of p
 	s int // start of line index
 	l int // line index number
+	c int // column number when the line may be dropped from the input, or 0
 }
 
 func (p pos) String() string {
@@ -19,6 +21,17 @@ func (p pos) String() string {
 	return fmt.Sprintf("pos{b: %d, s: %d, l:%d}", p.b, p.s, p.l)
 }
 
+// Pos is a position in the QJSON text.
+type Pos struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number in runes, starting at 1
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("line %d col %d", p.Line, p.Column)
+}
+
 type tokenTag byte
 
 const (
@@ -35,6 +48,11 @@ const (
 	tagOr
 	tagInverse
 	tagModulo
+	tagShiftLeft
+	tagShiftRight
+	tagAndNot
+	tagPower
+	tagIdentifier
 	tagOpenParen
 	tagCloseParen
 	tagWeeks
@@ -42,6 +60,7 @@ const (
 	tagHours
 	tagMinutes
 	tagSeconds
+	tagSize
 	tagOpenBrace
 	tagCloseBrace
 	tagOpenSquare
@@ -68,6 +87,11 @@ var tagStr = map[tokenTag]string{
 	tagOr:                 "tagOr",
 	tagInverse:            "tagInverse",
 	tagModulo:             "tagModulo",
+	tagShiftLeft:          "tagShiftLeft",
+	tagShiftRight:         "tagShiftRight",
+	tagAndNot:             "tagAndNot",
+	tagPower:              "tagPower",
+	tagIdentifier:         "tagIdentifier",
 	tagOpenParen:          "tagOpenParen",
 	tagCloseParen:         "tagCloseParen",
 	tagWeeks:              "tagWeeks",
@@ -75,6 +99,7 @@ var tagStr = map[tokenTag]string{
 	tagHours:              "tagHours",
 	tagMinutes:            "tagMinutes",
 	tagSeconds:            "tagSeconds",
+	tagSize:               "tagSize",
 	tagOpenBrace:          "tagOpenBrace",
 	tagCloseBrace:         "tagCloseBrace",
 	tagOpenSquare:         "tagOpenSquare",
@@ -128,9 +153,27 @@ func (t token) String() string {
 // A tokenizer produces QJSON tokens from input.
 type tokenizer struct {
 	pos
-	in []byte // input text
-	p  []byte // text left to parse
-	tk token
+	in   []byte // input text
+	p    []byte // text left to parse
+	tk   token
+	off  int       // index of in[0] in the input text
+	r    io.Reader // source of the input text, or nil if in is the input text
+	eof  bool      // the end of r has been reached
+	rerr error     // error returned by r other than io.EOF
+
+	offCol    int // number of columns in front of in[0] when the start of its line was dropped
+	offMargin pos // end of the margin of the line of in[0] when its start was dropped
+
+	keepComments bool   // record the comments in comments, kept by init
+	comments     []span // comments met since the last call to nextToken
+
+	isExpr func(p []byte) bool // number expression test when not nil, kept by init
+}
+
+// A span is the input text from pos to e.
+type span struct {
+	pos
+	e int
 }
 
 // init resets the tokenizer. Requires that nexToken() is called afterward.
@@ -139,6 +182,109 @@ func (tk *tokenizer) init(in []byte) {
 	tk.in = in
 	tk.p = tk.in
 	tk.tk = token{}
+	tk.off = 0
+	tk.r = nil
+	tk.eof = false
+	tk.rerr = nil
+	tk.offCol = 0
+	tk.offMargin = pos{}
+	tk.comments = tk.comments[:0]
+}
+
+// initReader resets the tokenizer to read its input text from r. Requires
+// that nexToken() is called afterward.
+func (tk *tokenizer) initReader(r io.Reader) {
+	tk.init(nil)
+	tk.r = r
+}
+
+// text returns the input text from index b to e.
+func (tk *tokenizer) text(b, e int) []byte {
+	return tk.in[b-tk.off : e-tk.off]
+}
+
+const (
+	// minLookahead is the number of bytes that must follow a token to be
+	// sure that it has been recognized as if the whole input was available.
+	minLookahead = 64
+
+	// readSize is the minimum number of bytes read from the input stream.
+	readSize = 32 * 1024
+)
+
+// fill drops the input text in front of start and reads more input text
+// from tk.r. Sets tk.eof when the end of tk.r is reached. The start of the
+// line of start is kept when it is a margin, otherwise the number of
+// columns of the dropped text of the line is kept in tk.offCol.
+func (tk *tokenizer) fill(start pos) {
+	keep := start.b - 13 // look-back of lenISODateTime
+	if keep <= start.s || start.s >= tk.off && getMargin(tk.text(start.s, keep)) == keep-start.s {
+		keep = start.s
+	}
+	if keep < tk.off {
+		keep = tk.off
+	}
+	for keep > start.s && keep > tk.off && tk.in[keep-tk.off]&0xC0 == 0x80 {
+		keep-- // keep the whole first character
+	}
+	if keep > start.s && keep > tk.off {
+		from := tk.off
+		if from <= start.s {
+			// the start of the line of start is dropped
+			from = start.s
+			m := start.s + getMargin(tk.text(start.s, keep))
+			tk.offMargin = pos{b: m, s: start.s, l: start.l, c: column(tk.text(start.s, m)) + 1}
+			tk.offCol = 0
+		}
+		tk.offCol += column(tk.text(from, keep))
+	}
+	kept := tk.in[keep-tk.off:]
+	size := len(kept) + readSize
+	if len(kept) > readSize {
+		size = 2 * len(kept)
+	}
+	buf := tk.in[:cap(tk.in)]
+	if cap(buf) < size {
+		buf = make([]byte, size)
+	}
+	n := copy(buf, kept)
+	for {
+		m, err := tk.r.Read(buf[n:size])
+		n += m
+		if err != nil {
+			tk.eof = true
+			if err != io.EOF {
+				tk.rerr = err
+			}
+			break
+		}
+		if m > 0 {
+			break
+		}
+	}
+	tk.in = buf[:n]
+	tk.off = keep
+}
+
+// keepColumn returns p with its column number set if the line of p may be
+// dropped from the input text.
+func (tk *tokenizer) keepColumn(p pos) pos {
+	if tk.r != nil {
+		p.c = tk.column(p)
+	}
+	return p
+}
+
+// column returns the column number of p.
+func (tk *tokenizer) column(p pos) int {
+	if p.c != 0 {
+		return p.c
+	}
+	if p.s < tk.off {
+		// the start of the line of p was dropped by fill
+		return tk.offCol + column(tk.text(tk.off, p.b)) + 1
+	}
+	return column(tk.text(p.s, p.b)) + 1
 }
 
 // whitespace returns the byte size of the first whitechar of p.
@@ -191,6 +337,11 @@ func (tk *tokenizer) popNewline() bool {
 	return false
 }
 
+// position returns the public position of p.
+func (tk *tokenizer) position(p pos) Pos {
+	return Pos{Offset: p.b, Line: p.l + 1, Column: tk.column(p)}
+}
+
 func (tk *tokenizer) token() token {
 	return tk.tk
 }
@@ -303,31 +454,41 @@ func column(p []byte) int {
 // skipRestOfLine pops all characters until an error occurs, a newline is met, or the
 // end of input is met. In the later case no error is returned.
 func (tk *tokenizer) skipRestOfLine() *atError {
-	for {
-		if tk.popNewline() || len(tk.p) == 0 {
-			return nil
-		}
+	if err := tk.skipLineText(); err != nil {
+		return err
+	}
+	tk.popNewline()
+	return nil
+}
+
+// skipLineText pops all characters until an error occurs, a newline is met, or the
+// end of input is met. The newline is not popped.
+func (tk *tokenizer) skipLineText() *atError {
+	for len(tk.p) != 0 && newline(tk.p) == 0 {
 		n, err := tk.char()
 		if err != nil {
 			return err
 		}
 		tk.popBytes(n)
 	}
+	return nil
 }
 
 // skipLineComment return true and nil error if it successfully skipped #... or //... comments
 // including the newline or the end of input is reached. Otherwise return false with the error.
 func (tk *tokenizer) skipLineComment() (bool, *atError) {
-	if len(tk.p) == 0 {
+	if !isLineComment(tk.p) {
 		return false, nil
 	}
-	if tk.p[0] == '#' || (tk.p[0] == '/' && len(tk.p) >= 2 && tk.p[1] == '/') {
-		if err := tk.skipRestOfLine(); err != nil {
-			return false, err
-		}
-		return true, nil
+	if err := tk.skipRestOfLine(); err != nil {
+		return false, err
 	}
-	return false, nil
+	return true, nil
+}
+
+// isLineComment returns true if p starts with a #... or //... comment.
+func isLineComment(p []byte) bool {
+	return len(p) != 0 && (p[0] == '#' || (p[0] == '/' && len(p) >= 2 && p[1] == '/'))
 }
 
 // skipMultilineComment return false and nil when tk.p is not the start of a
@@ -385,13 +546,14 @@ func (tk *tokenizer) doubleQuotedString() ([]byte, *atError) {
 		if len(tk.p) == 0 {
 			return nil, &atError{pos: startPos, err: ErrUnclosedDoubleQuoteString}
 		}
-		if tk.p[0] == '\\' && len(tk.p) > 1 && tk.p[1] == '"' {
+		if tk.p[0] == '\\' && len(tk.p) > 1 && (tk.p[1] == '"' || tk.p[1] == '\\') {
+			// an escaped quote or backslash
 			tk.popBytes(2)
 			continue
 		}
 		if tk.p[0] == '"' {
 			tk.popBytes(1)
-			return tk.in[startPos.b:tk.b], nil
+			return tk.text(startPos.b, tk.b), nil
 		}
 		if newline(tk.p) != 0 {
 			return nil, &atError{pos: startPos, err: ErrNewlineInDoubleQuoteString}
@@ -417,13 +579,14 @@ func (tk *tokenizer) singleQuotedString() ([]byte, *atError) {
 		if len(tk.p) == 0 {
 			return nil, &atError{pos: startPos, err: ErrUnclosedSingleQuoteString}
 		}
-		if tk.p[0] == '\\' && len(tk.p) >= 2 && tk.p[1] == '\'' {
+		if tk.p[0] == '\\' && len(tk.p) >= 2 && (tk.p[1] == '\'' || tk.p[1] == '\\') {
+			// an escaped quote or backslash
 			tk.popBytes(2)
 			continue
 		}
 		if tk.p[0] == '\'' {
 			tk.popBytes(1)
-			return tk.in[startPos.b:tk.b], nil
+			return tk.text(startPos.b, tk.b), nil
 		}
 		if newline(tk.p) != 0 {
 			return nil, &atError{pos: startPos, err: ErrNewlineInSingleQuoteString}
@@ -440,8 +603,8 @@ func (tk *tokenizer) singleQuotedString() ([]byte, *atError) {
 // if the : bolongs to an ISO date time. If no, it returns 0, otherwise it returns the offset
 // to the first byte that doesn’t belong the the ISO date time.
 func (tk *tokenizer) lenISODateTime() int {
-	if tk.p[0] == ':' && tk.b >= 13 {
-		if n := parseISODateTimeLiteral(tk.in[tk.b-13:]); n > 13 {
+	if tk.p[0] == ':' && tk.b-tk.off >= 13 {
+		if n := parseISODateTimeLiteral(tk.in[tk.b-13-tk.off:]); n > 13 {
 			return n - 13
 		}
 	}
@@ -451,7 +614,8 @@ func (tk *tokenizer) lenISODateTime() int {
 // quotelessString include any valid characters until any of
 // , { } [ ] : \n \r\n // /*, the end of input or an error is met.
 // The : belonging to an ISO date time doesn’t terminate the
-// quoteless string.
+// quoteless string, nor the , in the parenthesis of a number
+// expression.
 // The quoteless string is right trimmed of whitespace characters.
 // It return nil, nil, when the quoteles string is empty.
 func (tk *tokenizer) quotelessString() ([]byte, *atError) {
@@ -467,6 +631,7 @@ func (tk *tokenizer) quotelessString() ([]byte, *atError) {
 	}
 	startPos := tk.pos
 	endIdx := startPos.b
+	var depth int // parenthesis depth
 	for {
 		if len(tk.p) == 0 {
 			break
@@ -479,6 +644,12 @@ func (tk *tokenizer) quotelessString() ([]byte, *atError) {
 			if (tk.p[0] == '/' && len(tk.p) > 1 && (tk.p[1] == '/' || tk.p[1] == '*')) ||
 				newline(tk.p) != 0 || (tk.p[0] != '\r' && tk.p[0] != '/') {
 				// we met any of , { } [ ] # \n \r\n // /*
+				if tk.p[0] == ',' && depth > 0 && tk.numberExpr(tk.text(startPos.b, tk.b)) {
+					// the comma separates the arguments of a function
+					tk.popBytes(1)
+					endIdx = tk.b
+					continue
+				}
 				n := tk.lenISODateTime()
 				if n == 0 {
 					break
@@ -488,6 +659,12 @@ func (tk *tokenizer) quotelessString() ([]byte, *atError) {
 				continue
 			}
 		}
+		switch tk.p[0] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
 		n, err := tk.char()
 		if err != nil {
 			return nil, err
@@ -498,7 +675,15 @@ func (tk *tokenizer) quotelessString() ([]byte, *atError) {
 	if startPos.b == endIdx {
 		return nil, nil
 	}
-	return tk.in[startPos.b:endIdx], nil
+	return tk.text(startPos.b, endIdx), nil
+}
+
+// numberExpr returns true if p is a number expression.
+func (tk *tokenizer) numberExpr(p []byte) bool {
+	if tk.isExpr != nil {
+		return tk.isExpr(p)
+	}
+	return isNumberExpr(p)
 }
 
 var tkTagTable = [256]tokenTag{
@@ -528,14 +713,17 @@ func (tk *tokenizer) skipSpaces() *atError {
 	var ok bool
 	for err == nil && len(tk.p) > 0 {
 		tk.skipWhitespaces()
-		if ok, err = tk.skipLineComment(); ok || err != nil {
-			continue
-		}
-		if ok, err = tk.skipMultilineComment(); ok || err != nil {
+		start := tk.pos
+		if isLineComment(tk.p) {
+			err = tk.skipLineText()
+		} else if ok, err = tk.skipMultilineComment(); !ok && err == nil {
+			if !tk.popNewline() {
+				break
+			}
 			continue
 		}
-		if !tk.popNewline() {
-			break
+		if err == nil && tk.keepComments {
+			tk.comments = append(tk.comments, span{pos: start, e: tk.b})
 		}
 	}
 	return err
@@ -588,11 +776,15 @@ func (tk *tokenizer) multilineString() ([]byte, *atError) {
 	if len(tk.p) == 0 || tk.p[0] != '`' {
 		return nil, nil
 	}
-	b := getMargin(tk.in[tk.s:tk.b]) + tk.s
+	if tk.s < tk.off {
+		// the start of the line is only dropped when it is not a margin
+		return nil, &atError{pos: tk.offMargin, err: ErrMarginMustBeWhitespaceOnly}
+	}
+	b := getMargin(tk.text(tk.s, tk.b)) + tk.s
 	if b != tk.b {
 		return nil, &atError{pos: pos{b: b, s: tk.s, l: tk.l}, err: ErrMarginMustBeWhitespaceOnly}
 	}
-	margin := tk.in[tk.s:b]
+	margin := tk.text(tk.s, b)
 	startPos := tk.pos // for error reporting
 	tk.popBytes(1)     // pops starting `
 	tk.skipWhitespaces()
@@ -640,7 +832,7 @@ func (tk *tokenizer) multilineString() ([]byte, *atError) {
 		if tk.p[0] == '`' {
 			tk.popBytes(1)
 			if len(tk.p) == 0 || tk.p[0] != '\\' {
-				return tk.in[startPos.s:tk.b], nil // we reached the end of the multiline
+				return tk.text(startPos.s, tk.b), nil // we reached the end of the multiline
 			}
 			continue
 		}
@@ -660,6 +852,19 @@ func (tk *tokenizer) nextToken() {
 	if tk.tk.tag == tagError {
 		return
 	}
+	start := tk.pos
+	tk.scanToken()
+	for tk.r != nil && !tk.eof && len(tk.p) < minLookahead {
+		// the token may be truncated by the end of the input buffer
+		tk.fill(start)
+		tk.pos = start
+		tk.p = tk.in[start.b-tk.off:]
+		tk.scanToken()
+	}
+}
+
+// scanToken reads the next token from the input buffer.
+func (tk *tokenizer) scanToken() {
 	var tokenPos pos
 	if err := tk.skipSpaces(); err != nil {
 		tk.tk = token{tag: tagError, pos: err.pos, val: err.err}
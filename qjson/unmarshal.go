@@ -0,0 +1,617 @@
+package qjson
+
+import (
+	"encoding"
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Unmarshal decodes the QJSON text in data and stores the result in the
+// value pointed to by v. The text is decoded in one pass, without an
+// intermediate JSON text.
+//
+// Unmarshal follows the rules of encoding/json: objects are stored in
+// structs, maps with string keys or empty interfaces, arrays in slices,
+// arrays or empty interfaces, numbers in integer or floating point values,
+// and null sets pointers, interfaces, maps and slices to nil. Struct fields
+// are matched by the name given in the qjson tag, falling back to the json
+// tag and to the field name, preferring an exact match but also accepting
+// a case-insensitive match. Members without a matching field are ignored.
+func Unmarshal(data []byte, v interface{}) error {
+	return UnmarshalWithOptions(data, v, DecodeOptions{})
+}
+
+// UnmarshalWithOptions is like Unmarshal with the decoding options opts.
+func UnmarshalWithOptions(data []byte, v interface{}, opts DecodeOptions) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	var e engine
+	e.opts = opts
+	e.init(data)
+	e.b = &valueBuilder{root: rv}
+	e.document()
+	return e.err()
+}
+
+// An InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
+// The argument must be a non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "qjson: Unmarshal(nil)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "qjson: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "qjson: Unmarshal(nil " + e.Type.String() + ")"
+}
+
+// An UnmarshalTypeError describes a QJSON value that can't be stored in a
+// value of a specific Go type.
+type UnmarshalTypeError struct {
+	Value string       // description of the value: "string", "array", "number 3.5", ...
+	Type  reflect.Type // type of the Go value it could not be assigned to
+	Field string       // path of the struct field holding the value, if any
+	Pos                // position of the value in the QJSON text
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	if e.Field != "" {
+		return "cannot unmarshal " + e.Value + " into Go struct field " + e.Field +
+			" of type " + e.Type.String() + " at " + e.Pos.String()
+	}
+	return "cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String() +
+		" at " + e.Pos.String()
+}
+
+// A frame is an object or array being decoded.
+type frame struct {
+	v     reflect.Value // struct, map, slice or array receiving the values
+	dst   reflect.Value // interface receiving v when closed, if valid
+	key   reflect.Value // map key of the current member
+	elem  reflect.Value // value receiving the current member or array value
+	field string        // name of the struct field of the current member
+	n     int           // number of array values
+}
+
+// valueBuilder stores the values in Go values.
+type valueBuilder struct {
+	root  reflect.Value // pointer to the top level value
+	stack []frame
+	skip  int // depth of the object or array being ignored
+}
+
+var (
+	valueSliceType = reflect.TypeOf([]interface{}(nil))
+	valueMapType   = reflect.TypeOf(map[string]interface{}(nil))
+)
+
+// next returns the value receiving the next value. The returned value is
+// invalid when the value must be ignored.
+func (b *valueBuilder) next() reflect.Value {
+	if len(b.stack) == 0 {
+		return b.root
+	}
+	f := &b.stack[len(b.stack)-1]
+	switch f.v.Kind() {
+	case reflect.Slice:
+		if f.n == f.v.Len() {
+			f.v.Set(reflect.Append(f.v, reflect.Zero(f.v.Type().Elem())))
+		}
+		f.elem = f.v.Index(f.n)
+		f.n++
+	case reflect.Array:
+		if f.n < f.v.Len() {
+			f.elem = f.v.Index(f.n)
+		} else {
+			f.elem = reflect.Value{}
+		}
+		f.n++
+	}
+	return f.elem
+}
+
+// stored is called when a value has been decoded. It stores the value in
+// its map if needed.
+func (b *valueBuilder) stored() {
+	if len(b.stack) == 0 {
+		return
+	}
+	f := &b.stack[len(b.stack)-1]
+	if f.v.Kind() == reflect.Map && f.key.IsValid() {
+		f.v.SetMapIndex(f.key, f.elem)
+		f.key = reflect.Value{}
+	}
+}
+
+// fieldPath returns the path of the struct field receiving the current value.
+func (b *valueBuilder) fieldPath() string {
+	var names []string
+	for i := range b.stack {
+		if b.stack[i].field != "" {
+			names = append(names, b.stack[i].field)
+		}
+	}
+	return strings.Join(names, ".")
+}
+
+func (b *valueBuilder) typeError(e *engine, what string, t reflect.Type) {
+	e.setError(&UnmarshalTypeError{Value: what, Type: t, Field: b.fieldPath()})
+}
+
+// indirect walks down v allocating pointers as needed, until it gets to a
+// non-pointer. If it encounters a TextUnmarshaler, it stops and returns it.
+// If decodingNull is true, it stops at the last pointer so it can be set
+// to nil.
+func indirect(v reflect.Value, decodingNull bool) (encoding.TextUnmarshaler, reflect.Value) {
+	// a named addressable value may have a TextUnmarshaler pointer method
+	v0 := v
+	haveAddr := false
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		haveAddr = true
+		v = v.Addr()
+	}
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			x := v.Elem()
+			if x.Kind() == reflect.Ptr && !x.IsNil() && (!decodingNull || x.Elem().Kind() == reflect.Ptr) {
+				v = x
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			break
+		}
+		if decodingNull && v.CanSet() && v.Elem().Kind() != reflect.Ptr {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+				return u, reflect.Value{}
+			}
+		}
+		if haveAddr {
+			v = v0
+			haveAddr = false
+		} else {
+			v = v.Elem()
+		}
+	}
+	return nil, v
+}
+
+func (b *valueBuilder) openObject(e *engine) {
+	if b.skip > 0 {
+		b.skip++
+		return
+	}
+	t := b.next()
+	if !t.IsValid() {
+		b.skip = 1
+		return
+	}
+	u, t := indirect(t, false)
+	if u != nil {
+		b.typeError(e, "object", reflect.TypeOf(u))
+		return
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			b.typeError(e, "object", t.Type())
+			return
+		}
+		// a merged object is stored in the map of the previous member
+		m := t.Elem()
+		if e.opts.Duplicates != DuplicateMerge || !m.IsValid() || m.Type() != valueMapType {
+			m = reflect.MakeMap(valueMapType)
+			t.Set(m)
+		}
+		b.stack = append(b.stack, frame{v: m})
+	case reflect.Map:
+		switch t.Type().Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			b.typeError(e, "object", t.Type())
+			return
+		}
+		if t.IsNil() {
+			t.Set(reflect.MakeMap(t.Type()))
+		}
+		b.stack = append(b.stack, frame{v: t})
+	case reflect.Struct:
+		b.stack = append(b.stack, frame{v: t})
+	default:
+		b.typeError(e, "object", t.Type())
+	}
+}
+
+func (b *valueBuilder) closeObject(e *engine) {
+	if b.skip > 0 {
+		b.skip--
+		return
+	}
+	b.stack = b.stack[:len(b.stack)-1]
+	b.stored()
+}
+
+func (b *valueBuilder) openArray(e *engine) {
+	if b.skip > 0 {
+		b.skip++
+		return
+	}
+	t := b.next()
+	if !t.IsValid() {
+		b.skip = 1
+		return
+	}
+	u, t := indirect(t, false)
+	if u != nil {
+		b.typeError(e, "array", reflect.TypeOf(u))
+		return
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			b.typeError(e, "array", t.Type())
+			return
+		}
+		b.stack = append(b.stack, frame{v: reflect.New(valueSliceType).Elem(), dst: t})
+	case reflect.Slice:
+		t.SetLen(0)
+		b.stack = append(b.stack, frame{v: t})
+	case reflect.Array:
+		b.stack = append(b.stack, frame{v: t})
+	default:
+		b.typeError(e, "array", t.Type())
+	}
+}
+
+func (b *valueBuilder) closeArray(e *engine) {
+	if b.skip > 0 {
+		b.skip--
+		return
+	}
+	f := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
+	switch f.v.Kind() {
+	case reflect.Slice:
+		if f.v.IsNil() {
+			f.v.Set(reflect.MakeSlice(f.v.Type(), 0, 0))
+		}
+	case reflect.Array:
+		for i := f.n; i < f.v.Len(); i++ {
+			f.v.Index(i).Set(reflect.Zero(f.v.Type().Elem()))
+		}
+	}
+	if f.dst.IsValid() {
+		f.dst.Set(f.v)
+	}
+	b.stored()
+}
+
+func (b *valueBuilder) memberName(e *engine) {
+	if b.skip > 0 {
+		return
+	}
+	name, ok := e.stringValue()
+	if !ok {
+		return
+	}
+	f := &b.stack[len(b.stack)-1]
+	f.elem = reflect.Value{}
+	switch f.v.Kind() {
+	case reflect.Struct:
+		f.field = name
+		if fld := cachedFields(f.v.Type()).lookup(name); fld != nil {
+			f.elem = fieldByIndex(f.v, fld.index)
+		}
+		if e.dupKey && f.elem.IsValid() {
+			switch e.opts.Duplicates {
+			case DuplicateFirstWins:
+				f.elem = reflect.Value{}
+			case DuplicateLastWins:
+				f.elem.Set(reflect.Zero(f.elem.Type()))
+			}
+		}
+	case reflect.Map:
+		kt := f.v.Type().Key()
+		key := reflect.New(kt).Elem()
+		switch kt.Kind() {
+		case reflect.String:
+			key.SetString(name)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(name, 10, 64)
+			if err != nil || key.OverflowInt(n) {
+				b.typeError(e, "string "+strconv.Quote(name), kt)
+				return
+			}
+			key.SetInt(n)
+		default:
+			n, err := strconv.ParseUint(name, 10, 64)
+			if err != nil || key.OverflowUint(n) {
+				b.typeError(e, "string "+strconv.Quote(name), kt)
+				return
+			}
+			key.SetUint(n)
+		}
+		if e.dupKey && e.opts.Duplicates == DuplicateFirstWins {
+			return
+		}
+		f.key = key
+		f.elem = reflect.New(f.v.Type().Elem()).Elem()
+		if e.opts.Duplicates == DuplicateMerge {
+			// the objects are merged into the value of the previous member
+			if prev := f.v.MapIndex(key); prev.IsValid() {
+				f.elem.Set(prev)
+			}
+		}
+	}
+}
+
+// fieldByIndex returns the field of struct v with the given index,
+// allocating the embedded struct pointers as needed. It returns an invalid
+// value if an embedded pointer can't be allocated.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+func (b *valueBuilder) stringValue(e *engine) {
+	if b.skip > 0 {
+		return
+	}
+	t := b.next()
+	if !t.IsValid() {
+		return
+	}
+	s, ok := e.stringValue()
+	if !ok {
+		return
+	}
+	u, t := indirect(t, false)
+	if u != nil {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			e.setError(err)
+			return
+		}
+		b.stored()
+		return
+	}
+	switch {
+	case t.Kind() == reflect.String:
+		t.SetString(s)
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		t.Set(reflect.ValueOf(s))
+	case t.Kind() == reflect.Slice && t.Type().Elem().Kind() == reflect.Uint8:
+		d, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			b.typeError(e, "string", t.Type())
+			return
+		}
+		t.SetBytes(d)
+	default:
+		b.typeError(e, "string", t.Type())
+		return
+	}
+	b.stored()
+}
+
+func (b *valueBuilder) literalValue(e *engine, lit string) {
+	if b.skip > 0 {
+		return
+	}
+	t := b.next()
+	if !t.IsValid() {
+		return
+	}
+	if lit == "null" {
+		_, t = indirect(t, true)
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			t.Set(reflect.Zero(t.Type()))
+		}
+		b.stored()
+		return
+	}
+	u, t := indirect(t, false)
+	switch {
+	case u != nil:
+		b.typeError(e, "bool", reflect.TypeOf(u))
+		return
+	case t.Kind() == reflect.Bool:
+		t.SetBool(lit == "true")
+	case t.Kind() == reflect.Interface && t.NumMethod() == 0:
+		t.Set(reflect.ValueOf(lit == "true"))
+	default:
+		b.typeError(e, "bool", t.Type())
+		return
+	}
+	b.stored()
+}
+
+func (b *valueBuilder) numberValue(e *engine, num string) {
+	if b.skip > 0 {
+		return
+	}
+	t := b.next()
+	if !t.IsValid() {
+		return
+	}
+	u, t := indirect(t, false)
+	if u != nil {
+		b.typeError(e, "number "+num, reflect.TypeOf(u))
+		return
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil || t.OverflowInt(n) {
+			b.typeError(e, "number "+num, t.Type())
+			return
+		}
+		t.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(num, 10, 64)
+		if err != nil || t.OverflowUint(n) {
+			b.typeError(e, "number "+num, t.Type())
+			return
+		}
+		t.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(num, t.Type().Bits())
+		if err != nil || t.OverflowFloat(n) {
+			b.typeError(e, "number "+num, t.Type())
+			return
+		}
+		t.SetFloat(n)
+	case reflect.Interface:
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil || t.NumMethod() != 0 {
+			b.typeError(e, "number "+num, t.Type())
+			return
+		}
+		t.Set(reflect.ValueOf(n))
+	default:
+		b.typeError(e, "number "+num, t.Type())
+		return
+	}
+	b.stored()
+}
+
+// A field is a struct field that may hold a member value.
+type field struct {
+	name      string
+	index     []int
+	tagged    bool // name is given by a tag
+	omitEmpty bool
+}
+
+type fields []field
+
+// lookup returns the field with the given name, or a case-insensitive
+// match if there is none. It returns nil if there is no match.
+func (fs fields) lookup(name string) *field {
+	for i := range fs {
+		if fs[i].name == name {
+			return &fs[i]
+		}
+	}
+	for i := range fs {
+		if strings.EqualFold(fs[i].name, name) {
+			return &fs[i]
+		}
+	}
+	return nil
+}
+
+var fieldCache sync.Map // map[reflect.Type]fields
+
+// cachedFields returns the fields of struct type t.
+func cachedFields(t reflect.Type) fields {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.(fields)
+	}
+	fs, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return fs.(fields)
+}
+
+// parseTag returns the name and the options of the qjson tag of sf, or
+// of its json tag when it has no qjson tag.
+func parseTag(sf reflect.StructField) (string, string) {
+	tag, ok := sf.Tag.Lookup("qjson")
+	if !ok {
+		tag = sf.Tag.Get("json")
+	}
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		return tag[:i], tag[i:]
+	}
+	return tag, ""
+}
+
+// typeFields returns the fields of struct type t in declaration order.
+// The fields of embedded structs are promoted following the Go rules for
+// field selection.
+func typeFields(t reflect.Type) fields {
+	var all fields
+	var depths []int
+	var walk func(t reflect.Type, index []int, visited map[reflect.Type]bool)
+	walk = func(t reflect.Type, index []int, visited map[reflect.Type]bool) {
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if sf.PkgPath != "" && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+				continue // unexported
+			}
+			name, opts := parseTag(sf)
+			if name == "-" && opts == "" {
+				continue
+			}
+			idx := append(append([]int(nil), index...), i)
+			if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, idx, visited)
+				continue
+			}
+			if sf.PkgPath != "" {
+				continue
+			}
+			f := field{name: name, index: idx, tagged: name != "", omitEmpty: strings.Contains(opts, ",omitempty")}
+			if f.name == "" {
+				f.name = sf.Name
+			}
+			all = append(all, f)
+			depths = append(depths, len(idx))
+		}
+		delete(visited, t)
+	}
+	walk(t, nil, map[reflect.Type]bool{})
+
+	// keep the dominant field of each name
+	var fs fields
+	for i := range all {
+		dominant := true
+		for j := range all {
+			if i == j || all[i].name != all[j].name {
+				continue
+			}
+			if depths[j] < depths[i] || (depths[j] == depths[i] && (all[j].tagged || !all[i].tagged)) {
+				dominant = false
+				break
+			}
+		}
+		if dominant {
+			fs = append(fs, all[i])
+		}
+	}
+	return fs
+}
@@ -0,0 +1,154 @@
+package qjson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEmbedded struct {
+	Level int `qjson:"level"`
+}
+
+type testConfig struct {
+	testEmbedded
+	Name    string            `qjson:"name"`
+	Port    int               `json:"port"`
+	Ratio   float64           `qjson:"ratio,omitempty"`
+	Debug   bool              `qjson:"debug"`
+	Hosts   []string          `qjson:"hosts"`
+	Labels  map[string]string `qjson:"labels"`
+	Next    *testConfig       `qjson:"next"`
+	Any     interface{}       `qjson:"any"`
+	Skipped string            `qjson:"-"`
+	Pair    [2]int            `qjson:"pair"`
+	Codes   map[int]string    `qjson:"codes"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	in := `
+name: my server
+port: 0x1F90
+ratio: 1./4
+debug: yes
+hosts: [a.example.com, 'b.example.com']
+labels: {env: prod, "zone": eu}
+next: {name: backup, next: null}
+any: [1, two, {three: 3}, true, null]
+Skipped: ignored
+unknown: {a: [b, {c: d}]}
+pair: [1, 2, 3]
+codes: {200: ok}
+level: 3
+`
+	var c testConfig
+	if err := Unmarshal([]byte(in), &c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exp := testConfig{
+		testEmbedded: testEmbedded{Level: 3},
+		Name:         "my server",
+		Port:         8080,
+		Ratio:        0.25,
+		Debug:        true,
+		Hosts:        []string{"a.example.com", "b.example.com"},
+		Labels:       map[string]string{"env": "prod", "zone": "eu"},
+		Next:         &testConfig{Name: "backup"},
+		Any:          []interface{}{1., "two", map[string]interface{}{"three": 3.}, true, nil},
+		Pair:         [2]int{1, 2},
+		Codes:        map[int]string{200: "ok"},
+	}
+	if !reflect.DeepEqual(c, exp) {
+		t.Fatalf("expected %+v, got %+v", exp, c)
+	}
+
+	var m map[string]interface{}
+	if err := Unmarshal([]byte("a: [b]\nc:\n`\\n\n  x\n  `"), &m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exp := map[string]interface{}{"a": []interface{}{"b"}, "c": "  x\n  "}; !reflect.DeepEqual(m, exp) {
+		t.Fatalf("expected %v, got %v", exp, m)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		in  string
+		err string
+	}{
+		// 0
+		{in: "port: abc", err: "cannot unmarshal string into Go struct field port of type int at line 1 col 7"},
+		{in: "name: 12", err: "cannot unmarshal number 12 into Go struct field name of type string at line 1 col 7"},
+		{in: "next: {\n  port: 1.5\n}", err: "cannot unmarshal number 1.5 into Go struct field next.port of type int at line 2 col 9"},
+		{in: "hosts: {}", err: "cannot unmarshal object into Go struct field hosts of type []string at line 1 col 8"},
+		{in: "debug: [true]", err: "cannot unmarshal array into Go struct field debug of type bool at line 1 col 8"},
+		// 5
+		{in: "name: a\nport:", err: "unexpected end of input at line 2 col 6"},
+		{in: "codes: {x: y}", err: "cannot unmarshal string \"x\" into Go struct field codes of type int at line 1 col 9"},
+		{in: "port: true", err: "cannot unmarshal bool into Go struct field port of type int at line 1 col 7"},
+	}
+	for i, test := range tests {
+		var c testConfig
+		err := Unmarshal([]byte(test.in), &c)
+		if out := e2s(err); out != test.err {
+			t.Fatalf("%d expected err %q, got %q", i, test.err, out)
+		}
+	}
+
+	var ute *UnmarshalTypeError
+	var c testConfig
+	if err := Unmarshal([]byte("\n  port: x"), &c); !errors.As(err, &ute) || ute.Line != 2 || ute.Column != 9 || ute.Offset != 9 {
+		t.Fatalf("expected *UnmarshalTypeError at line 2 col 9, got %v", err)
+	}
+	if err := Unmarshal([]byte("a:b"), c); e2s(err) != "qjson: Unmarshal(non-pointer qjson.testConfig)" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := Unmarshal([]byte("a:b"), nil); e2s(err) != "qjson: Unmarshal(nil)" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var i int
+	if err := Unmarshal([]byte("a:b"), &i); e2s(err) != "cannot unmarshal object into Go value of type int at line 1 col 1" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+// testLevel implements encoding.TextUnmarshaler with a pointer receiver.
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("invalid level")
+	}
+	return nil
+}
+
+func TestUnmarshalTextAndBytes(t *testing.T) {
+	var v struct {
+		Level  testLevel   `qjson:"level"`
+		Levels []testLevel `qjson:"levels"`
+		Data   []byte      `qjson:"data"`
+		When   time.Time   `qjson:"when"`
+	}
+	in := "level: high, levels: [low, high], data: 'aGVsbG8=', when: '2021-03-04T05:06:07Z'"
+	if err := Unmarshal([]byte(in), &v); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	when := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	if v.Level != 2 || !reflect.DeepEqual(v.Levels, []testLevel{1, 2}) || string(v.Data) != "hello" || !v.When.Equal(when) {
+		t.Fatalf("unexpected value %+v", v)
+	}
+	exp := "cannot unmarshal string into Go struct field data of type []uint8 at line 1 col 7"
+	if err := Unmarshal([]byte("data: not base64"), &v); e2s(err) != exp {
+		t.Fatalf("expected error %q, got %q", exp, e2s(err))
+	}
+	exp = "invalid level at line 1 col 8"
+	if err := Unmarshal([]byte("level: medium"), &v); e2s(err) != exp {
+		t.Fatalf("expected error %q, got %q", exp, e2s(err))
+	}
+}